@@ -1,15 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"github.com/gogoclouds/project-layout/internal/app/domain"
 	"github.com/gogoclouds/project-layout/pkg/app"
 	"github.com/gogoclouds/project-layout/pkg/conf"
 	"github.com/gogoclouds/project-layout/pkg/logger"
-	"github.com/gogoclouds/project-layout/pkg/registry/etcd"
-	clientv3 "go.etcd.io/etcd/client/v3"
-	"google.golang.org/grpc"
-	"time"
 )
 
 var filepath = flag.String("config", "config/config.yaml", "config file path")
@@ -23,9 +20,6 @@ func init() {
 }
 
 func main() {
-	etcdClient := getEtcdClient()
-	defer etcdClient.Close()
-
 	newApp := app.New(
 		app.WithConfig(*filepath),
 		app.WithLogger(),
@@ -33,20 +27,9 @@ func main() {
 		app.WithRedis(),
 		app.WithGinServer(domain.LoadRouter),
 		app.WithGrpcServer(domain.RegisterServer),
-		app.WithRegistrar(etcd.New(etcdClient)),
+		app.WithEtcdRegistrar(),
 	)
-	if err := newApp.Run(); err != nil {
+	if err := newApp.Run(context.Background()); err != nil {
 		logger.Panic(err.Error())
 	}
 }
-
-func getEtcdClient() *clientv3.Client {
-	client, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{"127.0.0.1:2379"},
-		DialTimeout: time.Second, DialOptions: []grpc.DialOption{grpc.WithBlock()},
-	})
-	if err != nil {
-		panic(err)
-	}
-	return client
-}
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"github.com/gogoclouds/project-layout/pkg/breaker"
 	"github.com/gogoclouds/project-layout/pkg/cache"
 	"github.com/gogoclouds/project-layout/pkg/db"
 	"github.com/gogoclouds/project-layout/pkg/enum"
 	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/ratelimit"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
 )
 
 var Conf *Service
@@ -15,14 +18,19 @@ type Service struct {
 	Env        enum.EnvType `yaml:"env"`
 	TimeFormat string       `yaml:"timeFormat"`
 	Server     struct {
-		Http Transport `yaml:"http"`
-		Rpc  Transport `yaml:"rpc"`
+		AdvertiseHost string    `yaml:"advertiseHost"` // 注册到注册中心的对外 host, 为空时使用监听地址
+		Http          Transport `yaml:"http"`
+		Rpc           Transport `yaml:"rpc"`
+		Admin         Transport `yaml:"admin"` // 管理/调试服务 (pprof、日志级别等), addr 为空时不启动, 应只监听内网地址
 	}
-	KV       KV              `yaml:"kv"`
-	Logger   logger.Config   `yaml:"logger"`
-	Registry Transport       `yaml:"registry"`
-	DB       db.Config       `yaml:"db"`
-	Redis    cache.RedisConf `yaml:"redis"`
+	KV        KV               `yaml:"kv"`
+	Logger    logger.Config    `yaml:"logger"`
+	Registry  Transport        `yaml:"registry"`
+	DB        db.Config        `yaml:"db"`
+	Redis     cache.RedisConf  `yaml:"redis"`
+	Trace     tracing.Config   `yaml:"trace"`
+	Breaker   breaker.Config   `yaml:"breaker"`   // 客户端熔断, 见 App.Deps().Breaker
+	RateLimit ratelimit.Config `yaml:"rateLimit"` // 服务端限流
 }
 
 // Transport 传输协议
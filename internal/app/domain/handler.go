@@ -4,27 +4,30 @@ import (
 	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/gogoclouds/project-layout/api/admin/v1/helloworld"
+	"github.com/gogoclouds/project-layout/pkg/app"
 	"google.golang.org/grpc"
 )
 
-func LoadRouter(e *gin.Engine) {
+func LoadRouter(e *gin.Engine, deps *app.Deps) {
 	e.MaxMultipartMemory = 300 << 20 //MB
 
 	//noAuthRouterGroup := e.Group("")
-	//admin.NoAuthRouterRegister(noAuthRouterGroup, g.DB)
+	//admin.NoAuthRouterRegister(noAuthRouterGroup, deps.DB)
 
 	//authRouterGroup := e.Group("")
 	//authRouterGroup.Use(middleware.JWTAuth())
 
-	//admin.RouterRegister(authRouterGroup, g.DB)
+	//admin.RouterRegister(authRouterGroup, deps.DB)
 }
 
-func RegisterServer(server *grpc.Server) {
-	helloworld.RegisterGreeterServer(server, &GreeterService{})
+func RegisterServer(server *grpc.Server, deps *app.Deps) {
+	helloworld.RegisterGreeterServer(server, &GreeterService{deps: deps})
 }
 
 type GreeterService struct {
 	helloworld.UnimplementedGreeterServer
+
+	deps *app.Deps
 }
 
 func (h *GreeterService) SayHello(ctx context.Context, in *helloworld.HelloRequest) (*helloworld.HelloReply, error) {
@@ -2,12 +2,21 @@ package app
 
 import (
 	"context"
-	"github.com/gogoclouds/project-layout/pkg/host"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/config"
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	"github.com/gogoclouds/project-layout/pkg/election"
+	"github.com/gogoclouds/project-layout/pkg/health"
+	"github.com/gogoclouds/project-layout/pkg/ratelimit"
 	"github.com/gogoclouds/project-layout/pkg/server"
-	"net"
+	"github.com/gogoclouds/project-layout/pkg/server/rpc"
+	"github.com/redis/go-redis/v9"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
 	"os"
 	"os/signal"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,166 +24,271 @@ import (
 	"github.com/gogoclouds/project-layout/pkg/logger"
 	"github.com/gogoclouds/project-layout/pkg/registry"
 	"github.com/gogoclouds/project-layout/pkg/util"
+	"github.com/gogoclouds/project-layout/pkg/worker"
 )
 
+// advertiseHostEnv 对外 host 的环境变量
+const advertiseHostEnv = "ADVERTISE_HOST"
+
 type App struct {
-	opts options
+	opts   options
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	mu sync.Mutex
 
-	instance *registry.ServiceInstance
+	// Init 解析出的组件
+	initialized bool
+	conf        *config.Service
+	db          *gorm.DB
+	redis       redis.UniversalClient
+	etcdClient  *clientv3.Client
+	// tracingShutdown 上报剩余的 span, 未启用链路追踪时为 nil
+	tracingShutdown func(context.Context) error
+	registrar       registry.ServiceRegistrar
+	breaker         *breaker.Group      // 配置 breaker.kind 时非 nil
+	rateLimit       *ratelimit.Limiters // 配置 rateLimit.rules 时非 nil
+	election        election.Election
+	health          *health.Checker
+	workers         []*worker.Worker // WithWorker 及 WithLeaderTask 的后台任务
+	// releaseRpcBreaker 取消 server.RpcDial 使用的熔断
+	releaseRpcBreaker func()
+
+	instances  []*registry.ServiceInstance // 主服务在首位
+	registered bool
+
+	// Start 启动的传输服务
+	started bool
+	servers []server.Server
+	eg      *errgroup.Group
+	done    <-chan struct{}
+
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
 func New(opts ...Option) *App {
 	o := options{
-		wg:              &sync.WaitGroup{},
 		id:              util.UUID(),
 		sigs:            []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT},
 		registryTimeout: 10 * time.Second,
-		exit:            make(chan struct{}),
+		stopTimeout:     defaultStopTimeout,
 	}
 
 	for _, opt := range opts {
 		opt(&o)
 	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &App{
-		opts: o,
+		opts:   o,
+		ctx:    ctx,
+		cancel: cancel,
+		health: health.New(),
 	}
 }
 
-// Run run server
-// 1.注册服务
-// 2.退出相关组件或服务
-func (a *App) Run() error {
-	instance, err := a.buildInstance()
-	if err != nil {
+// Start 初始化组件并启动服务, 服务全部就绪并注册后返回
+// 1.Init 初始化组件
+// 2.绑定监听地址并启动所有传输服务
+// 3.注册服务
+// ctx 只约束启动过程, 启动失败时已启动的部分会按 Shutdown 流程关闭.
+func (a *App) Start(ctx context.Context) error {
+	if err := a.Init(ctx); err != nil {
 		return err
 	}
 	a.mu.Lock()
-	a.instance = instance
+	if a.started {
+		a.mu.Unlock()
+		return errors.New("app already started")
+	}
+	a.started = true
 	a.mu.Unlock()
 
-	opts := a.opts
-
-	if opts.httpServer != nil {
-		go server.RunHttpServer(opts.exit, opts.wg, opts.conf.Server.Http.Addr, opts.httpServer)
+	primary := a.buildServers()
+	servers := primary
+	for _, svc := range a.opts.services {
+		servers = append(servers, svc.Servers...)
+	}
+	if addr := a.conf.Server.Admin.Addr; addr != "" {
+		servers = append(servers, server.NewAdminServer(addr,
+			server.WithAdminConfig(a.conf),
+			server.WithAdminInstances(func() any { return a.Instances() })))
 	}
+	a.mu.Lock()
+	a.servers = servers
+	a.mu.Unlock()
 
-	if opts.rpcServer != nil {
-		go server.RunRpcServer(opts.exit, opts.wg, opts.conf.Server.Rpc.Addr, opts.rpcServer)
+	opts := a.opts
+	// 所有传输服务先绑定监听地址, 任一失败则直接返回, 不会注册到注册中心
+	err := listenServers(servers)
+	if err != nil {
+		logger.Errorf("listen server error: %v", err)
+		return errors.Join(err, a.Shutdown(context.Background()))
+	}
+	// 使用真实监听地址构造注册实例
+	instances, err := a.buildInstances(primary)
+	if err != nil {
+		return errors.Join(err, a.Shutdown(context.Background()))
 	}
+	a.mu.Lock()
+	a.instances = instances
+	a.mu.Unlock()
 
-	ctx := context.Background()
+	eg, sctx := errgroup.WithContext(a.ctx)
+	for _, srv := range servers {
+		srv := srv
+		eg.Go(func() error {
+			return srv.Start(sctx)
+		})
+	}
+	a.mu.Lock()
+	a.eg, a.done = eg, sctx.Done()
+	a.mu.Unlock()
 
-	for _, fn := range a.opts.beforeStart {
+	for _, fn := range opts.beforeStart {
 		if err = fn(ctx); err != nil {
-			return err
+			return errors.Join(err, a.Shutdown(context.Background()))
 		}
 	}
 
+	// 启动阶段已有传输服务异常退出
+	if sctx.Err() != nil {
+		return a.Shutdown(context.Background())
+	}
+
 	// 注册服务
-	if opts.registrar != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), opts.registryTimeout)
-		defer cancel()
-		if err := opts.registrar.Registry(ctx, instance); err != nil {
+	if a.registrar != nil {
+		rctx, rcancel := context.WithTimeout(ctx, opts.registryTimeout)
+		err = a.register(rctx)
+		rcancel()
+		if err != nil {
 			logger.Errorf("register service error: %v", err)
-			return err
+			return errors.Join(err, a.Shutdown(context.Background()))
 		}
 	}
 
-	for _, fn := range a.opts.afterStart {
+	// 依赖组件及自定义检查项全部通过后才置为就绪
+	if err = a.health.Check(ctx).Err(); err != nil {
+		logger.Errorf("health check error: %v", err)
+		return errors.Join(err, a.Shutdown(context.Background()))
+	}
+	a.health.SetReady(true)
+
+	for _, fn := range opts.afterStart {
 		if err = fn(ctx); err != nil {
-			return err
+			return errors.Join(err, a.Shutdown(context.Background()))
 		}
 	}
+	return nil
+}
 
-	// 监听退出信号
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, opts.sigs...)
-	<-c
-
-	if err = a.Stop(); err != nil {
-		logger.Errorf("stop service error: %v", err)
-	}
-	for _, fn := range a.opts.beforeStop {
-		err = fn(ctx)
-	}
+// Shutdown 按阶段优雅关闭应用, 见 shutdown.
+// ctx 可在 WithStopTimeout 之外进一步缩短截止时间; 重复调用只关闭一次, 返回相同结果.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.shutdownOnce.Do(func() {
+		a.cancel() // 通知传输服务、Run 退出
+		a.mu.Lock()
+		servers, eg := a.servers, a.eg
+		a.mu.Unlock()
 
-	close(opts.exit) // 通知http、rpc服务退出信号
+		err := a.shutdown(ctx, servers)
+		if eg != nil {
+			// 聚合传输服务运行期间的错误
+			if serveErr := eg.Wait(); serveErr != nil && !errors.Is(serveErr, context.Canceled) {
+				err = errors.Join(serveErr, err)
+			}
+		}
+		a.shutdownErr = err
+	})
+	return a.shutdownErr
+}
 
-	// 1.等待 Http 服务结束退出
-	// 2.等待 RPC 服务结束退出
-	a.opts.wg.Wait()
+// Run 启动应用并阻塞, 直到收到退出信号、ctx 取消、Stop 被调用或传输服务异常退出,
+// 随后优雅关闭并返回聚合的错误
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Start(ctx); err != nil {
+		logger.Errorf("start service error: %v", err)
+		return err
+	}
 
-	for _, fn := range a.opts.afterStop {
-		err = fn(ctx)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, a.opts.sigs...)
+	defer signal.Stop(c)
+	select {
+	case sig := <-c:
+		logger.Infof("received signal %s, shutting down", sig)
+	case <-ctx.Done():
+		logger.Infof("context done, shutting down: %v", ctx.Err())
+	case <-a.done:
 	}
 
-	opts.wg.Wait()
+	if err := a.Shutdown(context.Background()); err != nil {
+		logger.Errorf("service exited with error: %v", err)
+		return err
+	}
 	logger.Info("service has exited")
-	return err
+	return nil
 }
 
-// Stop stop server
-// 1.注销服务
+// Stop 通知 Run 开始优雅关闭, 关闭结果由 Run 返回
 func (a *App) Stop() error {
-	a.mu.Lock()
-	instance := a.instance
-	a.mu.Unlock()
-	if a.opts.registrar != nil && instance != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), a.opts.registryTimeout)
-		defer cancel()
-		if err := a.opts.registrar.Deregister(ctx, instance); err != nil {
-			logger.Errorf("deregister service error: %w", err)
-			return err
-		}
+	if a.cancel != nil {
+		a.cancel()
 	}
 	return nil
 }
 
-func (a *App) buildInstance() (*registry.ServiceInstance, error) {
-	endpoints := make([]string, 0)
-	httpScheme, grpcScheme := false, false
-	for _, e := range a.opts.endpoints {
-		switch strings.ToLower(e.Scheme) {
-		case "https", "http":
-			httpScheme = true
-		case "grpc":
-			grpcScheme = true
+// listenServers 依次绑定传输服务的监听地址, 遇到失败立即返回
+func listenServers(servers []server.Server) error {
+	for _, srv := range servers {
+		if lis, ok := srv.(server.Listener); ok {
+			if err := lis.Listen(); err != nil {
+				return err
+			}
 		}
-		endpoints = append(endpoints, e.String())
 	}
-	if !httpScheme {
-		if rUrl, err := getRegistryUrl("http", a.opts.conf.Server.Http.Addr); err == nil {
-			endpoints = append(endpoints, rUrl)
-		} else {
-			logger.Errorf("get http registry err:%v", err)
+	return nil
+}
+
+// buildServers 按配置构造 Gin、gRPC 传输服务, 并追加 WithServer 注入的自定义服务及后台任务
+func (a *App) buildServers() []server.Server {
+	servers := make([]server.Server, 0, len(a.opts.servers)+2)
+	deps := a.Deps()
+	if a.opts.httpServer != nil {
+		httpOpts := []server.HttpOption{server.WithServiceInfo(a.conf.Name, a.conf.Version), server.WithHealth(a.health),
+			server.WithRateLimit(a.rateLimit), server.WithAccessLog(a.opts.httpAccessLog...)}
+		if a.opts.metricsRoute {
+			httpOpts = append(httpOpts, server.WithMetricsRoute())
 		}
+		servers = append(servers, server.NewHttpServer(a.conf.Server.Http.Addr, func(e *gin.Engine) {
+			a.opts.httpServer(e, deps)
+		}, httpOpts...))
 	}
-	if !grpcScheme {
-		if rUrl, err := getRegistryUrl("grpc", a.opts.conf.Server.Rpc.Addr); err == nil {
-			endpoints = append(endpoints, rUrl)
-		} else {
-			logger.Errorf("get grpc registry err:%v", err)
+	if a.opts.rpcServer != nil {
+		rpcOpts := []rpc.ServerOption{rpc.WithAddress(a.conf.Server.Rpc.Addr), rpc.WithHealth(a.health),
+			rpc.WithRateLimit(a.rateLimit), rpc.WithAccessLog(a.opts.rpcAccessLog...)}
+		if timeout, err := time.ParseDuration(a.conf.Server.Rpc.Timeout); err == nil {
+			rpcOpts = append(rpcOpts, rpc.WithTimeout(timeout))
 		}
+		srv := rpc.NewServer(rpcOpts...)
+		a.opts.rpcServer(srv.Server, deps)
+		servers = append(servers, srv)
+	}
+	servers = append(servers, a.opts.servers...)
+	for _, w := range a.workers {
+		servers = append(servers, w)
 	}
-	return &registry.ServiceInstance{
-		ID:        a.opts.id,
-		Name:      a.opts.conf.Name,
-		Version:   a.opts.conf.Version,
-		Metadata:  nil,
-		Endpoints: endpoints,
-	}, nil
+	return servers
 }
 
-func getRegistryUrl(scheme, addr string) (string, error) {
-	ip, err := host.OutBoundIP()
-	if err != nil {
-		return "", err
+// advertiseHost 注册到注册中心的对外 host (NAT、容器场景),
+// 优先级: WithAdvertiseHost > 环境变量 ADVERTISE_HOST > 配置 server.advertiseHost
+func (a *App) advertiseHost() string {
+	if a.opts.advertiseHost != "" {
+		return a.opts.advertiseHost
 	}
-	_, ports, err := net.SplitHostPort(addr)
-	if err != nil {
-		return "", err
+	if h := os.Getenv(advertiseHostEnv); h != "" {
+		return h
 	}
-	return scheme + "://" + net.JoinHostPort(ip, ports), nil
+	return a.conf.Server.AdvertiseHost
 }
@@ -0,0 +1,645 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/pkg/app"
+	"github.com/gogoclouds/project-layout/pkg/election"
+	"github.com/gogoclouds/project-layout/pkg/ratelimit"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/server"
+	"github.com/gogoclouds/project-layout/pkg/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeConfig 生成测试用配置文件, 日志写入临时目录
+func writeConfig(t *testing.T, extra string) string {
+	t.Helper()
+	dir := t.TempDir()
+	content := fmt.Sprintf(`name: app-test
+version: '0.0.1'
+env: dev
+server:
+  http:
+    addr: '127.0.0.1:0'
+  rpc:
+    addr: '127.0.0.1:0'
+logger:
+  level: info
+  filepath: '%s'
+%s`, dir, extra)
+	filename := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestInit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("without config", func(t *testing.T) {
+		if err := app.New(app.WithLogger()).Init(ctx); err == nil {
+			t.Fatal("expected config error")
+		}
+	})
+
+	t.Run("config not found", func(t *testing.T) {
+		err := app.New(app.WithConfig(filepath.Join(t.TempDir(), "none.yaml"))).Init(ctx)
+		if err == nil || !strings.Contains(err.Error(), "config") {
+			t.Fatalf("expected config error, got: %v", err)
+		}
+	})
+
+	t.Run("logger before config", func(t *testing.T) {
+		a := app.New(app.WithLogger(), app.WithConfig(writeConfig(t, "")))
+		if err := a.Init(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if a.Conf().Name != "app-test" {
+			t.Fatalf("unexpected config: %+v", a.Conf())
+		}
+	})
+
+	t.Run("aggregated component errors", func(t *testing.T) {
+		filename := writeConfig(t, `db:
+  source: root:root@tcp(127.0.0.1:1)/none
+redis:
+  addrs: []
+`)
+		err := app.New(app.WithConfig(filename), app.WithDB(), app.WithRedis()).Init(ctx)
+		if err == nil {
+			t.Fatal("expected init error")
+		}
+		for _, component := range []string{"db:", "redis:"} {
+			if !strings.Contains(err.Error(), component) {
+				t.Errorf("error %q does not mention %s", err, component)
+			}
+		}
+	})
+}
+
+func newTestApp(t *testing.T, opts ...app.Option) *app.App {
+	t.Helper()
+	opts = append([]app.Option{
+		app.WithConfig(writeConfig(t, "")),
+		app.WithGinServer(func(e *gin.Engine, deps *app.Deps) {}),
+		app.WithGrpcServer(func(s *grpc.Server, deps *app.Deps) {}),
+	}, opts...)
+	return app.New(opts...)
+}
+
+// endpoints 启动后实例注册的 http、grpc 地址
+func endpoints(t *testing.T, a *app.App) (httpAddr, rpcAddr string) {
+	t.Helper()
+	instance := a.Instance()
+	if instance == nil {
+		t.Fatal("app is not started")
+	}
+	for _, e := range instance.Endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch u.Scheme {
+		case server.KindHTTP:
+			httpAddr = u.Host
+		case server.KindGRPC:
+			rpcAddr = u.Host
+		}
+	}
+	if httpAddr == "" || rpcAddr == "" {
+		t.Fatalf("unexpected endpoints: %v", instance.Endpoints)
+	}
+	return httpAddr, rpcAddr
+}
+
+func checkHttpHealth(t *testing.T, addr string) {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "app-test") {
+		t.Fatalf("unexpected health response: %s %s", resp.Status, body)
+	}
+}
+
+func checkHttpStatus(t *testing.T, url string, code int) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != code {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("GET %s: expected %d, got %s %s", url, code, resp.Status, body)
+	}
+}
+
+func checkRpcHealth(t *testing.T, addr string) {
+	t.Helper()
+	checkRpcStatus(t, addr, healthgrpc.HealthCheckResponse_SERVING)
+}
+
+func checkRpcStatus(t *testing.T, addr string, status healthgrpc.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := healthgrpc.NewHealthClient(conn).Check(ctx, &healthgrpc.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != status {
+		t.Fatalf("expected rpc health status %s, got %s", status, resp.Status)
+	}
+}
+
+func TestStartShutdown(t *testing.T) {
+	var stopped []string
+	a := newTestApp(t,
+		app.BeforeStop(func(context.Context) error {
+			stopped = append(stopped, "beforeStop")
+			return nil
+		}),
+		app.AfterStop(func(context.Context) error {
+			stopped = append(stopped, "afterStop")
+			return errors.New("after stop failed")
+		}),
+	)
+	ctx := context.Background()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	httpAddr, rpcAddr := endpoints(t, a)
+	checkHttpHealth(t, httpAddr)
+	checkRpcHealth(t, rpcAddr)
+
+	err := a.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), "after stop failed") {
+		t.Fatalf("expected aggregated after stop error, got: %v", err)
+	}
+	if strings.Join(stopped, ",") != "beforeStop,afterStop" {
+		t.Fatalf("unexpected hook order: %v", stopped)
+	}
+	if _, err = http.Get("http://" + httpAddr + "/health"); err == nil {
+		t.Fatal("http server still serving after shutdown")
+	}
+}
+
+func TestMetricsRoute(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []app.Option
+		code int
+	}{
+		{name: "default", code: http.StatusNotFound},
+		{name: "enabled", opts: []app.Option{app.WithMetricsRoute()}, code: http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, tt.opts...)
+			ctx := context.Background()
+			if err := a.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+			defer a.Shutdown(ctx)
+			httpAddr, _ := endpoints(t, a)
+			checkHttpStatus(t, "http://"+httpAddr+"/metrics", tt.code)
+		})
+	}
+}
+
+func TestStartListenError(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	a := app.New(
+		app.WithConfig(writeConfig(t, "")),
+		app.WithServer(server.NewHttpServer(lis.Addr().String(), func(e *gin.Engine) {})),
+	)
+	err = a.Start(context.Background())
+	var listenErr *server.ListenError
+	if !errors.As(err, &listenErr) || listenErr.Kind != server.KindHTTP {
+		t.Fatalf("expected http listen error, got: %v", err)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	a := newTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- a.Run(ctx)
+	}()
+
+	// 等待服务就绪
+	deadline := time.Now().Add(3 * time.Second)
+	for !a.Health().Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("app not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	httpAddr, rpcAddr := endpoints(t, a)
+	checkHttpHealth(t, httpAddr)
+	checkRpcHealth(t, rpcAddr)
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after context cancel")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	var a *app.App
+	a = newTestApp(t,
+		app.WithHealthCheck("custom", func(context.Context) error { return nil }),
+		app.BeforeStart(func(context.Context) error {
+			// 启动 hook 执行期间尚未就绪
+			httpAddr, rpcAddr := endpoints(t, a)
+			checkHttpStatus(t, "http://"+httpAddr+"/livez", http.StatusOK)
+			checkHttpStatus(t, "http://"+httpAddr+"/readyz", http.StatusServiceUnavailable)
+			checkRpcStatus(t, rpcAddr, healthgrpc.HealthCheckResponse_NOT_SERVING)
+			return nil
+		}),
+		app.BeforeStop(func(context.Context) error {
+			// 开始关闭时立即置为未就绪
+			httpAddr, rpcAddr := endpoints(t, a)
+			checkHttpStatus(t, "http://"+httpAddr+"/readyz", http.StatusServiceUnavailable)
+			checkRpcStatus(t, rpcAddr, healthgrpc.HealthCheckResponse_NOT_SERVING)
+			return nil
+		}),
+	)
+	ctx := context.Background()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	httpAddr, rpcAddr := endpoints(t, a)
+	checkHttpStatus(t, "http://"+httpAddr+"/readyz", http.StatusOK)
+	checkRpcHealth(t, rpcAddr)
+	if err := a.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartHealthCheckFailed(t *testing.T) {
+	a := newTestApp(t,
+		app.WithHealthCheck("custom", func(context.Context) error { return errors.New("not warmed up") }),
+	)
+	err := a.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "custom: not warmed up") {
+		t.Fatalf("expected health check error, got: %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	a := newTestApp(t,
+		app.WithConfig(writeConfig(t, `rateLimit:
+  rules:
+    - target: 'GET /ping'
+      limit: 1
+      window: 1m
+    - target: 'GET /users/:id'
+      limit: 1
+      window: 1m
+      key: user
+`)),
+		app.WithRateLimitOptions(ratelimit.WithHTTPKey("user", func(c *gin.Context) string {
+			return c.Param("id")
+		})),
+		app.WithGinServer(func(e *gin.Engine, deps *app.Deps) {
+			e.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+			e.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, c.Param("id")) })
+		}),
+	)
+	ctx := context.Background()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Shutdown(ctx)
+	httpAddr, _ := endpoints(t, a)
+	checkHttpStatus(t, "http://"+httpAddr+"/ping", http.StatusOK)
+	checkHttpStatus(t, "http://"+httpAddr+"/ping", http.StatusTooManyRequests)
+	// 自定义 key 按用户分别计数
+	checkHttpStatus(t, "http://"+httpAddr+"/users/1", http.StatusOK)
+	checkHttpStatus(t, "http://"+httpAddr+"/users/1", http.StatusTooManyRequests)
+	checkHttpStatus(t, "http://"+httpAddr+"/users/2", http.StatusOK)
+}
+
+// memRegistrar 内存注册中心, 记录注册、更新、注销的实例
+type memRegistrar struct {
+	mu        sync.Mutex
+	instances map[string]*registry.ServiceInstance
+	updates   int
+	batches   int
+}
+
+func newMemRegistrar() *memRegistrar {
+	return &memRegistrar{instances: make(map[string]*registry.ServiceInstance)}
+}
+
+func (r *memRegistrar) Registry(_ context.Context, si *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instances[si.ID] = si
+	return nil
+}
+
+func (r *memRegistrar) Update(_ context.Context, si *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instances[si.ID] = si
+	r.updates++
+	return nil
+}
+
+func (r *memRegistrar) Deregister(_ context.Context, si *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.instances, si.ID)
+	return nil
+}
+
+func (r *memRegistrar) BatchDeregister(_ context.Context, instances ...*registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, si := range instances {
+		delete(r.instances, si.ID)
+	}
+	r.batches++
+	return nil
+}
+
+func (r *memRegistrar) get(id string) *registry.ServiceInstance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.instances[id]
+}
+
+func TestMetadata(t *testing.T) {
+	reg := newMemRegistrar()
+	a := newTestApp(t,
+		app.WithId("md-test"),
+		app.WithRegistrar(reg),
+		app.WithMetadata(map[string]string{registry.MetadataZone: "zone-a"}),
+	)
+	ctx := context.Background()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Shutdown(ctx)
+
+	si := reg.get("md-test")
+	if si == nil {
+		t.Fatal("instance not registered")
+	}
+	for _, key := range []string{registry.MetadataEnv, registry.MetadataHostname, registry.MetadataStartTime, registry.MetadataGoVersion} {
+		if si.Metadata[key] == "" {
+			t.Errorf("metadata %s is empty: %v", key, si.Metadata)
+		}
+	}
+	if si.Metadata[registry.MetadataEnv] != "dev" || si.Metadata[registry.MetadataZone] != "zone-a" {
+		t.Fatalf("unexpected metadata: %v", si.Metadata)
+	}
+
+	if err := a.SetMetadata(ctx, map[string]string{registry.MetadataDraining: "true"}); err != nil {
+		t.Fatal(err)
+	}
+	if si = reg.get("md-test"); si.Metadata[registry.MetadataDraining] != "true" || reg.updates != 1 {
+		t.Fatalf("metadata not updated: %v", si.Metadata)
+	}
+	if a.Instance().Metadata[registry.MetadataDraining] != "true" {
+		t.Fatalf("instance not updated: %v", a.Instance().Metadata)
+	}
+}
+
+func TestAdvertiseHost(t *testing.T) {
+	a := newTestApp(t, app.WithAdvertiseHost("10.0.0.1"))
+	ctx := context.Background()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Shutdown(ctx)
+
+	for _, e := range a.Instance().Endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Hostname() != "10.0.0.1" || u.Port() == "" || u.Port() == "0" {
+			t.Fatalf("unexpected endpoint: %s", e)
+		}
+	}
+}
+
+func TestMultiService(t *testing.T) {
+	reg := newMemRegistrar()
+	a := newTestApp(t,
+		app.WithId("main"),
+		app.WithRegistrar(reg),
+		app.WithService(app.Service{
+			ID:       "worker",
+			Name:     "worker-service",
+			Metadata: map[string]string{registry.MetadataZone: "zone-b"},
+			Servers: []server.Server{server.NewHttpServer("127.0.0.1:0", func(e *gin.Engine) {},
+				server.WithServiceInfo("app-test", "v1"))},
+		}),
+	)
+	ctx := context.Background()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(a.Instances()); n != 2 {
+		t.Fatalf("expected 2 instances, got %d", n)
+	}
+	main, worker := reg.get("main"), reg.get("worker")
+	if main == nil || worker == nil {
+		t.Fatal("instances not registered")
+	}
+	if worker.Name != "worker-service" || worker.Version != main.Version {
+		t.Fatalf("unexpected worker instance: %+v", worker)
+	}
+	if worker.Metadata[registry.MetadataZone] != "zone-b" || main.Metadata[registry.MetadataZone] != "" {
+		t.Fatalf("unexpected metadata: main %v, worker %v", main.Metadata, worker.Metadata)
+	}
+	if len(worker.Endpoints) != 1 || len(main.Endpoints) != 2 {
+		t.Fatalf("unexpected endpoints: main %v, worker %v", main.Endpoints, worker.Endpoints)
+	}
+	u, err := url.Parse(worker.Endpoints[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHttpHealth(t, u.Host)
+
+	if err = a.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if reg.get("main") != nil || reg.get("worker") != nil || reg.batches != 1 {
+		t.Fatal("instances not deregistered atomically")
+	}
+}
+
+func TestWorker(t *testing.T) {
+	fail := make(chan struct{})
+	var stopped atomic.Bool
+	a := newTestApp(t,
+		app.WithWorker("consumer", func(ctx context.Context) error {
+			<-ctx.Done()
+			stopped.Store(true)
+			return nil
+		}),
+		app.WithWorker("job", func(ctx context.Context) error {
+			<-fail
+			return errors.New("job failed")
+		}, worker.WithMaxRestarts(0)),
+		app.WithWorker("sync", func(ctx context.Context) error {
+			return errors.New("sync failed")
+		}, worker.WithInterval(10*time.Millisecond), worker.WithBackoff(time.Hour, time.Hour)),
+	)
+	ctx := context.Background()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	httpAddr, _ := endpoints(t, a)
+	checkHttpStatus(t, "http://"+httpAddr+"/readyz", http.StatusOK)
+
+	// 就绪报告中输出任务运行状态
+	var status worker.Status
+	deadline := time.Now().Add(2 * time.Second)
+	for status.Restarts == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		var body struct {
+			Data struct {
+				Checks map[string]struct {
+					Details worker.Status `json:"details"`
+				} `json:"checks"`
+			} `json:"data"`
+		}
+		resp, err := http.Get("http://" + httpAddr + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		status = body.Data.Checks["worker:sync"].Details
+	}
+	if status.State != worker.StateBackoff || status.Restarts != 1 || status.LastError != "sync failed" || status.NextRun.IsZero() {
+		t.Fatalf("unexpected worker status: %+v", status)
+	}
+
+	// 重启次数耗尽后未就绪
+	close(fail)
+	deadline = time.Now().Add(2 * time.Second)
+	for a.Ready(ctx) == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	err := a.Ready(ctx)
+	if err == nil || !strings.Contains(err.Error(), "worker:job") {
+		t.Fatalf("expected worker check error, got: %v", err)
+	}
+	checkHttpStatus(t, "http://"+httpAddr+"/readyz", http.StatusServiceUnavailable)
+
+	if err = a.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !stopped.Load() {
+		t.Fatal("worker not stopped on shutdown")
+	}
+}
+
+func TestLeaderTask(t *testing.T) {
+	e := election.NewMemory()
+	var running atomic.Value
+	newLeaderApp := func(id string) *app.App {
+		return newTestApp(t,
+			app.WithId(id),
+			app.WithElection(e),
+			app.WithLeaderTask("cleanup", func(ctx context.Context) error {
+				running.Store(id)
+				<-ctx.Done()
+				running.CompareAndSwap(id, "")
+				return nil
+			}, worker.WithBackoff(50*time.Millisecond, 100*time.Millisecond)),
+		)
+	}
+	waitLeader := func(id string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for running.Load() != id && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if running.Load() != id {
+			t.Fatalf("leader task running on %v, want %s", running.Load(), id)
+		}
+	}
+
+	ctx := context.Background()
+	a1, a2 := newLeaderApp("a1"), newLeaderApp("a2")
+	if err := a1.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitLeader("a1")
+	if err := a2.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer a2.Shutdown(ctx)
+
+	// 失去领导权后取消任务, 由另一实例接管
+	e.Revoke("app-test/cleanup")
+	waitLeader("a2")
+
+	// 关闭时取消任务并放弃领导权
+	if err := a2.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitLeader("a1")
+	if err := a1.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitLeader("")
+
+	a3 := newTestApp(t, app.WithLeaderTask("cleanup", func(ctx context.Context) error { return nil }))
+	if err := a3.Init(ctx); err == nil || !strings.Contains(err.Error(), "election") {
+		t.Fatalf("expected election error, got: %v", err)
+	}
+}
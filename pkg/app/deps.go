@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/gogoclouds/project-layout/config"
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	"github.com/gogoclouds/project-layout/pkg/health"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Deps App 托管的依赖组件.
+// 通过 WithGinServer、WithGrpcServer 的注册函数传给业务层, 未启用的组件为 nil.
+type Deps struct {
+	Conf  *config.Service
+	DB    *gorm.DB
+	Redis redis.UniversalClient
+	// Discovery 注册中心支持服务发现时非 nil, 用于 client/grpc 等客户端解析 discovery:///<service>
+	Discovery registry.ServiceDiscovery
+	// Breaker 配置 breaker.kind 时非 nil, 用于 client/grpc.WithBreaker、client/http.WithBreaker
+	Breaker *breaker.Group
+}
+
+// Deps 返回 App 托管的依赖组件
+func (a *App) Deps() *Deps {
+	return &Deps{
+		Conf:      a.conf,
+		DB:        a.db,
+		Redis:     a.redis,
+		Discovery: a.Discovery(),
+		Breaker:   a.breaker,
+	}
+}
+
+// Conf 服务配置
+func (a *App) Conf() *config.Service {
+	return a.conf
+}
+
+// DB WithDB 初始化的数据库连接, 未启用时为 nil
+func (a *App) DB() *gorm.DB {
+	return a.db
+}
+
+// Redis WithRedis 初始化的 redis 客户端, 未启用时为 nil
+func (a *App) Redis() redis.UniversalClient {
+	return a.redis
+}
+
+// Discovery 服务发现, 注册中心未启用或不支持服务发现时为 nil
+func (a *App) Discovery() registry.ServiceDiscovery {
+	d, _ := a.registrar.(registry.ServiceDiscovery)
+	return d
+}
+
+// Health 就绪状态与检查项, 可供 WithServer 注入的自定义服务使用
+func (a *App) Health() *health.Checker {
+	return a.health
+}
+
+// Ready 就绪检查: App 处于就绪状态且所有检查项 (DB、Redis ping 及 WithHealthCheck) 通过
+func (a *App) Ready(ctx context.Context) error {
+	report := a.health.Check(ctx)
+	if !report.Ready {
+		return errors.Join(errors.New("app is not ready"), report.Err())
+	}
+	return report.Err()
+}
+
+// pingDB 就绪检查项: ping 数据库
+func (a *App) pingDB(ctx context.Context) error {
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// pingRedis 就绪检查项: ping redis
+func (a *App) pingRedis(ctx context.Context) error {
+	return a.redis.Ping(ctx).Err()
+}
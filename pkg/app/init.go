@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/gogoclouds/project-layout/config"
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	"github.com/gogoclouds/project-layout/pkg/cache"
+	"github.com/gogoclouds/project-layout/pkg/conf"
+	"github.com/gogoclouds/project-layout/pkg/db"
+	etcdelection "github.com/gogoclouds/project-layout/pkg/election/etcd"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/ratelimit"
+	"github.com/gogoclouds/project-layout/pkg/registry/etcd"
+	"github.com/gogoclouds/project-layout/pkg/server"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
+	"github.com/gogoclouds/project-layout/pkg/worker"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gorm.io/driver/mysql"
+	"time"
+)
+
+// etcdDialTimeout 连接 etcd 注册中心的超时时间
+const etcdDialTimeout = 5 * time.Second
+
+// Init 按依赖顺序初始化组件: config → logger → tracing → breaker → DB → Redis → ratelimit → registry.
+// config 是其余组件的前提, 加载失败立即返回; 其余组件的错误聚合后一并返回,
+// 已初始化成功的组件会被释放. 重复调用只初始化一次, Run 会自动调用.
+func (a *App) Init(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.initialized {
+		return nil
+	}
+
+	if err := a.initConfig(); err != nil {
+		return fmt.Errorf("app init: config: %w", err)
+	}
+	if a.opts.logger {
+		a.conf.Logger.Filename = a.conf.Name
+		a.conf.Logger.TimeFormat = a.conf.TimeFormat
+		logger.InitZapLogger(a.conf.Logger)
+	}
+	if a.conf.Trace.Exporter != "" {
+		shutdown, err := tracing.Init(ctx, a.conf.Trace, a.conf.Name, a.conf.Version)
+		if err != nil {
+			return fmt.Errorf("app init: tracing: %w", err)
+		}
+		a.tracingShutdown = shutdown
+	}
+
+	var errs []error
+	if a.conf.Breaker.Kind != "" {
+		g, err := breaker.NewGroup(a.conf.Breaker)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			a.breaker = g
+			a.releaseRpcBreaker = server.UseRpcBreaker(g)
+		}
+	}
+	if a.opts.db {
+		newDB, err := db.NewDB(mysql.Open(a.conf.DB.Source), a.conf.DB)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("db: %w", err))
+		}
+		a.db = newDB
+	}
+	if a.opts.redis {
+		newRedis, err := cache.NewRedis(a.conf.Redis)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("redis: %w", err))
+		}
+		a.redis = newRedis
+	}
+	// 限流规则可能依赖 redis
+	rateLimit, err := ratelimit.New(a.conf.RateLimit, a.redis, a.opts.rateLimitOpts...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	a.rateLimit = rateLimit
+	a.registrar = a.opts.registrar
+	if a.opts.etcd {
+		if err := a.initEtcdRegistrar(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("registry: %w", err))
+		}
+	}
+	if err := a.initWorkers(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		_ = a.closeResources(ctx)
+		return fmt.Errorf("app init: %w", errors.Join(errs...))
+	}
+
+	// 就绪检查项
+	if a.db != nil {
+		a.health.AddCheck("db", a.pingDB)
+	}
+	if a.redis != nil {
+		a.health.AddCheck("redis", a.pingRedis)
+	}
+	for _, w := range a.workers {
+		a.health.AddDetailCheck("worker:"+w.Name(), w.CheckDetails)
+	}
+	for _, hc := range a.opts.healthChecks {
+		a.health.AddCheck(hc.name, hc.fn)
+	}
+	a.initialized = true
+	return nil
+}
+
+func (a *App) initConfig() error {
+	if a.opts.configFile == "" {
+		return errors.New("config file is required, use app.WithConfig")
+	}
+	c, err := conf.Load[config.Service](a.opts.configFile, func(e fsnotify.Event) {
+		//logger.S(config.Conf.Logger.Level)
+	})
+	if err != nil {
+		return err
+	}
+	a.conf = c
+	config.Conf = c
+	return nil
+}
+
+func (a *App) initEtcdRegistrar(ctx context.Context) error {
+	if a.conf.Registry.Addr == "" {
+		return errors.New("registry.addr is empty")
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{a.conf.Registry.Addr},
+		DialTimeout: etcdDialTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	// clientv3.New 不会阻塞等待连接建立, 通过 Status 确认 etcd 可用
+	sctx, cancel := context.WithTimeout(ctx, etcdDialTimeout)
+	defer cancel()
+	if _, err = client.Status(sctx, a.conf.Registry.Addr); err != nil {
+		_ = client.Close()
+		return err
+	}
+	a.etcdClient = client
+	a.registrar = etcd.New(client, a.opts.etcdOpts...)
+	return nil
+}
+
+// initWorkers 校验后台任务配置, 有单例任务时解析选主实现
+func (a *App) initWorkers() error {
+	workers := append([]*worker.Worker{}, a.opts.workers...)
+	if len(a.opts.leaderTasks) > 0 {
+		a.election = a.opts.election
+		if a.election == nil && a.etcdClient != nil {
+			a.election = etcdelection.New(a.etcdClient)
+		}
+		if a.election == nil {
+			return errors.New("worker: leader task requires an election, use app.WithElection or app.WithEtcdRegistrar")
+		}
+		for _, task := range a.opts.leaderTasks {
+			workers = append(workers, a.leaderWorker(task))
+		}
+	}
+	var errs []error
+	for _, w := range workers {
+		if err := w.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("worker: %w", err))
+		}
+	}
+	a.workers = workers
+	return errors.Join(errs...)
+}
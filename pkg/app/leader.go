@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	"github.com/gogoclouds/project-layout/pkg/election"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/worker"
+)
+
+type leaderTask struct {
+	name string
+	fn   func(ctx context.Context) error
+	opts []worker.Option
+}
+
+// WithElection 指定选主实现, 未指定时使用 WithEtcdRegistrar 的 etcd 客户端
+func WithElection(e election.Election) Option {
+	return func(o *options) {
+		o.election = e
+	}
+}
+
+// WithLeaderTask 添加单例任务, 多副本中只有当选 leader 的实例执行 fn.
+// fn 应持续运行直到 ctx 取消, 失去领导权或关闭时 ctx 取消;
+// 失去领导权后按 worker 的退避策略重新参与选主, fn 返回后放弃领导权由其余副本接管.
+// 选主名称为 <服务名>/<name>, opts 中的 WithInterval、WithCron 不适用于单例任务.
+func WithLeaderTask(name string, fn func(ctx context.Context) error, opts ...worker.Option) Option {
+	return func(o *options) {
+		o.leaderTasks = append(o.leaderTasks, leaderTask{name: name, fn: fn, opts: opts})
+	}
+}
+
+// leaderWorker 将单例任务包装为后台任务: 选主 → 执行 → 放弃领导权
+func (a *App) leaderWorker(task leaderTask) *worker.Worker {
+	key := path.Join(a.conf.Name, task.name)
+	return worker.New(task.name, func(ctx context.Context) error {
+		l, err := a.election.Campaign(ctx, key, a.opts.id)
+		if err != nil {
+			return err
+		}
+		logger.Infof("leader task %s: elected as leader", task.name)
+
+		lctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-l.Done():
+				cancel()
+			case <-lctx.Done():
+			}
+		}()
+		err = task.fn(lctx)
+
+		lost := false
+		select {
+		case <-l.Done():
+			lost = true
+		default:
+		}
+		// 关闭时 ctx 已取消, 放弃领导权使用独立的超时时间
+		rctx, rcancel := context.WithTimeout(context.WithoutCancel(ctx), a.opts.registryTimeout)
+		defer rcancel()
+		if rerr := l.Resign(rctx); rerr != nil && !lost {
+			logger.Errorf("leader task %s: resign error: %v", task.name, rerr)
+		}
+		if lost && ctx.Err() == nil {
+			logger.Errorf("leader task %s: leadership lost", task.name)
+			return errors.Join(election.ErrLeadershipLost, err)
+		}
+		return err
+	}, task.opts...)
+}
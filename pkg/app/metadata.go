@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/registry"
+)
+
+// buildMetadata 实例元数据: 运行环境、主机名、启动时间、构建信息, WithMetadata 可覆盖
+func (a *App) buildMetadata() map[string]string {
+	md := map[string]string{
+		registry.MetadataStartTime: time.Now().Format(time.RFC3339),
+	}
+	if a.conf != nil && a.conf.Env != "" {
+		md[registry.MetadataEnv] = string(a.conf.Env)
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		md[registry.MetadataHostname] = hostname
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		md[registry.MetadataGoVersion] = info.GoVersion
+		if info.Main.Version != "" {
+			md[registry.MetadataBuild] = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				md[registry.MetadataRevision] = setting.Value
+			}
+		}
+	}
+	maps.Copy(md, a.opts.metadata)
+	return md
+}
+
+// Instance 返回主服务实例信息的副本, Start 之前为 nil
+func (a *App) Instance() *registry.ServiceInstance {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.instances) == 0 {
+		return nil
+	}
+	return cloneInstance(a.instances[0])
+}
+
+// SetMetadata 运行时修改进程内所有实例的元数据 (如下线前标记 draining),
+// 已注册时同步更新到注册中心
+func (a *App) SetMetadata(ctx context.Context, md map[string]string) error {
+	a.mu.Lock()
+	if len(a.instances) == 0 {
+		a.mu.Unlock()
+		return errors.New("app is not started")
+	}
+	instances := make([]*registry.ServiceInstance, 0, len(a.instances))
+	for _, si := range a.instances {
+		instance := cloneInstance(si)
+		maps.Copy(instance.Metadata, md)
+		instances = append(instances, instance)
+	}
+	a.instances = instances
+	registered := a.registered
+	a.mu.Unlock()
+
+	if !registered || a.registrar == nil {
+		return nil
+	}
+	var errs []error
+	for _, instance := range instances {
+		if updater, ok := a.registrar.(registry.ServiceUpdater); ok {
+			errs = append(errs, updater.Update(ctx, instance))
+		} else {
+			// 注册中心不支持原地更新时重新注册
+			errs = append(errs, a.registrar.Registry(ctx, instance))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func cloneInstance(si *registry.ServiceInstance) *registry.ServiceInstance {
+	c := *si
+	c.Metadata = maps.Clone(si.Metadata)
+	if c.Metadata == nil {
+		c.Metadata = make(map[string]string)
+	}
+	c.Endpoints = append([]string(nil), si.Endpoints...)
+	return &c
+}
@@ -2,46 +2,64 @@ package app
 
 import (
 	"context"
-	"github.com/fsnotify/fsnotify"
 	"github.com/gin-gonic/gin"
-	"github.com/gogoclouds/project-layout/config"
-	"github.com/gogoclouds/project-layout/pkg/cache"
-	"github.com/gogoclouds/project-layout/pkg/conf"
-	"github.com/gogoclouds/project-layout/pkg/db"
-	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/election"
+	"github.com/gogoclouds/project-layout/pkg/health"
+	"github.com/gogoclouds/project-layout/pkg/ratelimit"
 	"github.com/gogoclouds/project-layout/pkg/registry"
-	"github.com/redis/go-redis/v9"
+	"github.com/gogoclouds/project-layout/pkg/registry/etcd"
+	"github.com/gogoclouds/project-layout/pkg/server"
+	"github.com/gogoclouds/project-layout/pkg/server/rpc/serverinterceptors"
+	"github.com/gogoclouds/project-layout/pkg/worker"
 	"google.golang.org/grpc"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
 	"net/url"
 	"os"
-	"sync"
 	"time"
 )
 
 type Option func(o *options)
 
 type options struct {
-	conf *config.Service
+	// 组件初始化意图, 由 App.Init 按依赖顺序解析
+	configFile string
+	logger     bool
+	db         bool
+	dbTables   [][]string
+	redis      bool
+	etcd       bool
+	etcdOpts   []etcd.Option
 
-	wg   *sync.WaitGroup
-	exit chan struct{}
+	rateLimitOpts []ratelimit.Option
+	httpAccessLog []server.AccessLogOption
+	rpcAccessLog  []serverinterceptors.LogOption
+	metricsRoute  bool
 
 	id        string
 	endpoints []*url.URL
+	metadata  map[string]string
+
+	advertiseHost string
 
 	sigs            []os.Signal
 	registrar       registry.ServiceRegistrar
 	registryTimeout time.Duration
-	httpServer      func(e *gin.Engine)
-	rpcServer       func(s *grpc.Server)
+	httpServer      func(e *gin.Engine, deps *Deps)
+	rpcServer       func(s *grpc.Server, deps *Deps)
+	servers         []server.Server
+	services        []Service
+	workers         []*worker.Worker
+	election        election.Election
+	leaderTasks     []leaderTask
+
+	// 优雅关闭
+	stopTimeout   time.Duration
+	drainDelay    time.Duration
+	phaseTimeouts map[Phase]time.Duration
+
+	healthChecks []healthCheck
 
 	// Before and After hook
 	beforeStart, beforeStop, afterStart, afterStop []func(context.Context) error
-
-	db    *gorm.DB
-	redis redis.UniversalClient
 }
 
 func WithId(id string) Option {
@@ -56,6 +74,25 @@ func WithEndpoints(endpoints []*url.URL) Option {
 	}
 }
 
+// WithMetadata 实例元数据, 覆盖 App 自动填充的同名 key (见 registry.Metadata*)
+func WithMetadata(md map[string]string) Option {
+	return func(o *options) {
+		if o.metadata == nil {
+			o.metadata = make(map[string]string, len(md))
+		}
+		for k, v := range md {
+			o.metadata[k] = v
+		}
+	}
+}
+
+// WithAdvertiseHost 注册到注册中心的对外 host, 替换监听地址中的 host, 保留真实端口
+func WithAdvertiseHost(host string) Option {
+	return func(o *options) {
+		o.advertiseHost = host
+	}
+}
+
 func WithSignal(sigs []os.Signal) Option {
 	return func(o *options) {
 		o.sigs = sigs
@@ -74,59 +111,140 @@ func WithRegistrarTimeout(rt time.Duration) Option {
 	}
 }
 
-func WithConfig(filename string) Option {
+// WithStopTimeout 优雅关闭的总截止时间, 超出后剩余阶段立即超时
+func WithStopTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.stopTimeout = d
+	}
+}
+
+// WithDrainDelay 注销服务后、停止传输服务前的等待时间, 让负载均衡感知实例下线
+func WithDrainDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.drainDelay = d
+	}
+}
+
+// WithPhaseTimeout 单个关闭阶段的超时时间, 默认 10s
+func WithPhaseTimeout(phase Phase, d time.Duration) Option {
 	return func(o *options) {
-		var err error
-		o.conf, err = conf.Load[config.Service](filename, func(e fsnotify.Event) {
-			//logger.S(config.Conf.Logger.Level)
-		})
-		if err != nil {
-			panic(err)
+		if o.phaseTimeouts == nil {
+			o.phaseTimeouts = make(map[Phase]time.Duration)
 		}
+		o.phaseTimeouts[phase] = d
+	}
+}
+
+// WithConfig 服务配置文件路径, 在 App.Init 时加载
+func WithConfig(filename string) Option {
+	return func(o *options) {
+		o.configFile = filename
 	}
 }
 
+// WithLogger 按服务配置初始化 zap 日志
 func WithLogger() Option {
 	return func(o *options) {
-		o.conf.Logger.Filename = o.conf.Name
-		o.conf.Logger.TimeFormat = o.conf.TimeFormat
-		logger.InitZapLogger(o.conf.Logger)
+		o.logger = true
 	}
 }
 
+// WithDB 按服务配置初始化 mysql 连接
 func WithDB(tables ...[]string) Option {
 	// TODO gorm.AutoMerge
 	return func(o *options) {
-		newDB, err := db.NewDB(mysql.Open(o.conf.DB.Source), o.conf.DB)
-		if err != nil {
-			logger.Panic(err.Error())
-		}
-		o.db = newDB
+		o.db = true
+		o.dbTables = tables
 	}
 }
 
+// WithRedis 按服务配置初始化 redis 客户端
 func WithRedis() Option {
 	return func(o *options) {
-		newRedis, err := cache.NewRedis(o.conf.Redis)
-		if err != nil {
-			logger.Panic(err.Error())
-		}
-		o.redis = newRedis
+		o.redis = true
+	}
+}
+
+// WithRateLimitOptions 限流选项, 如注册配置 rateLimit.rules 中使用的自定义计数 key (ratelimit.WithHTTPKey)
+func WithRateLimitOptions(opts ...ratelimit.Option) Option {
+	return func(o *options) {
+		o.rateLimitOpts = append(o.rateLimitOpts, opts...)
+	}
+}
+
+// WithEtcdRegistrar 按服务配置 registry.addr 连接 etcd 并作为注册中心,
+// etcd 客户端由 App 托管, 关闭时自动释放
+func WithEtcdRegistrar(opts ...etcd.Option) Option {
+	return func(o *options) {
+		o.etcd = true
+		o.etcdOpts = opts
 	}
 }
 
-func WithGinServer(router func(e *gin.Engine)) Option {
+// WithGinServer 注册 http 路由, deps 为 App 托管的依赖组件
+func WithGinServer(router func(e *gin.Engine, deps *Deps)) Option {
 	return func(o *options) {
 		o.httpServer = router
 	}
 }
 
-func WithGrpcServer(svr func(rpcServer *grpc.Server)) Option {
+// WithGrpcServer 注册 rpc 服务, deps 为 App 托管的依赖组件
+func WithGrpcServer(svr func(rpcServer *grpc.Server, deps *Deps)) Option {
 	return func(o *options) {
 		o.rpcServer = svr
 	}
 }
 
+// WithHttpAccessLog WithGinServer 的访问日志选项 (跳过路径、采样率、请求体等), 见 server.AccessLog
+func WithHttpAccessLog(opts ...server.AccessLogOption) Option {
+	return func(o *options) {
+		o.httpAccessLog = append(o.httpAccessLog, opts...)
+	}
+}
+
+// WithMetricsRoute WithGinServer 在业务端口注册 /metrics, 默认只由管理服务提供, 见 server.WithMetricsRoute
+func WithMetricsRoute() Option {
+	return func(o *options) {
+		o.metricsRoute = true
+	}
+}
+
+// WithRpcAccessLog WithGrpcServer 的访问日志选项 (慢调用阈值、请求体、脱敏字段等),
+// 见 serverinterceptors.UnaryLoggerInterceptor
+func WithRpcAccessLog(opts ...serverinterceptors.LogOption) Option {
+	return func(o *options) {
+		o.rpcAccessLog = append(o.rpcAccessLog, opts...)
+	}
+}
+
+// WithServer 追加自定义传输服务 (如管理端口、websocket、metrics 等),
+// 与 WithGinServer、WithGrpcServer 一起由 App 统一启动和停止
+func WithServer(srv ...server.Server) Option {
+	return func(o *options) {
+		o.servers = append(o.servers, srv...)
+	}
+}
+
+// WithWorker 添加后台任务 (队列消费者、定时任务等), 由 App 统一启动, 在 stop-servers 阶段取消.
+// 任务状态作为就绪检查项 worker:<name>, 详情为 worker.Status, 重启次数耗尽后 /readyz 返回失败, 见 worker.Option
+func WithWorker(name string, fn func(ctx context.Context) error, opts ...worker.Option) Option {
+	return func(o *options) {
+		o.workers = append(o.workers, worker.New(name, fn, opts...))
+	}
+}
+
+type healthCheck struct {
+	name string
+	fn   health.CheckFunc
+}
+
+// WithHealthCheck 添加就绪检查项, 启动时必须通过, 运行中由 /readyz 实时检查
+func WithHealthCheck(name string, fn func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.healthChecks = append(o.healthChecks, healthCheck{name: name, fn: fn})
+	}
+}
+
 // Before and Afters
 
 // BeforeStart run funcs before app starts
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/network"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/server"
+	"github.com/gogoclouds/project-layout/pkg/util"
+)
+
+// Service 同一进程内额外注册的逻辑服务 (如 admin API 与后台 worker API 共用一个二进制).
+// 主服务由配置文件的 name、version 及 WithGinServer、WithGrpcServer、WithServer 构成.
+type Service struct {
+	ID       string            // 实例 ID, 为空时随机生成
+	Name     string            // 服务名, 必填
+	Version  string            // 版本号, 为空时沿用主服务版本
+	Metadata map[string]string // 实例元数据, 覆盖 App 自动填充及 WithMetadata 的同名 key
+
+	// Servers 该服务独占的传输服务, 由 App 统一启动和停止, endpoints 取自真实监听地址
+	Servers []server.Server
+	// Endpoints 显式指定的 endpoints, 同一 scheme 以此为准
+	Endpoints []*url.URL
+}
+
+// WithService 注册额外的逻辑服务, 与主服务一起注册、一起注销
+func WithService(svc Service) Option {
+	return func(o *options) {
+		o.services = append(o.services, svc)
+	}
+}
+
+// Instances 返回进程内所有实例信息的副本, 主服务在首位
+func (a *App) Instances() []*registry.ServiceInstance {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	instances := make([]*registry.ServiceInstance, 0, len(a.instances))
+	for _, si := range a.instances {
+		instances = append(instances, cloneInstance(si))
+	}
+	return instances
+}
+
+// buildInstances 构造主服务及 WithService 的注册实例, 必须在传输服务绑定监听地址之后调用
+func (a *App) buildInstances(primary []server.Server) ([]*registry.ServiceInstance, error) {
+	md := a.buildMetadata()
+	main, err := a.newInstance(a.opts.id, a.conf.Name, a.conf.Version, md, a.opts.endpoints, primary)
+	if err != nil {
+		return nil, err
+	}
+	instances := []*registry.ServiceInstance{main}
+	for _, svc := range a.opts.services {
+		if svc.Name == "" {
+			return nil, errors.New("service name is required")
+		}
+		id, version := svc.ID, svc.Version
+		if id == "" {
+			id = util.UUID()
+		}
+		if version == "" {
+			version = a.conf.Version
+		}
+		svcMd := maps.Clone(md)
+		maps.Copy(svcMd, svc.Metadata)
+		si, err := a.newInstance(id, svc.Name, version, svcMd, svc.Endpoints, svc.Servers)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, si)
+	}
+	return instances, nil
+}
+
+// newInstance 构造注册实例.
+// endpoints 取自 explicit 与各传输服务的真实监听地址, 同一 scheme 以 explicit 为准;
+// 配置了对外地址 (见 advertiseHost) 时替换 endpoint 的 host, 保留真实端口.
+func (a *App) newInstance(id, name, version string, md map[string]string,
+	explicit []*url.URL, servers []server.Server) (*registry.ServiceInstance, error) {
+	endpoints := make([]string, 0)
+	schemes := make(map[string]bool)
+	for _, e := range explicit {
+		schemes[strings.ToLower(e.Scheme)] = true
+		endpoints = append(endpoints, e.String())
+	}
+	advertiseHost := a.advertiseHost()
+	for _, srv := range servers {
+		ep, ok := srv.(server.Endpointer)
+		if !ok {
+			continue
+		}
+		u, err := ep.Endpoint()
+		if err != nil {
+			return nil, err
+		}
+		if schemes[strings.ToLower(u.Scheme)] {
+			continue
+		}
+		if advertiseHost != "" {
+			u = network.NewEndpoint(u.Scheme, net.JoinHostPort(advertiseHost, u.Port()))
+		}
+		endpoints = append(endpoints, u.String())
+	}
+	return &registry.ServiceInstance{
+		ID:        id,
+		Name:      name,
+		Version:   version,
+		Metadata:  md,
+		Endpoints: endpoints,
+	}, nil
+}
+
+// register 注册所有实例, 任一失败则注销已注册的实例
+func (a *App) register(ctx context.Context) error {
+	instances := a.Instances()
+	registered := make([]*registry.ServiceInstance, 0, len(instances))
+	for _, si := range instances {
+		if err := a.registrar.Registry(ctx, si); err != nil {
+			if len(registered) > 0 {
+				if derr := a.deregisterAll(ctx, registered); derr != nil {
+					logger.Errorf("rollback registration error: %v", derr)
+				}
+			}
+			return err
+		}
+		registered = append(registered, si)
+	}
+	a.mu.Lock()
+	a.registered = true
+	a.mu.Unlock()
+	return nil
+}
+
+// deregister 从注册中心注销进程内所有实例
+func (a *App) deregister(ctx context.Context) error {
+	a.mu.Lock()
+	registered := a.registered
+	a.registered = false
+	a.mu.Unlock()
+	if a.registrar == nil || !registered {
+		return nil
+	}
+	return a.deregisterAll(ctx, a.Instances())
+}
+
+// deregisterAll 注册中心支持批量注销时原子地注销所有实例, 否则逐个注销并聚合错误
+func (a *App) deregisterAll(ctx context.Context, instances []*registry.ServiceInstance) error {
+	if batch, ok := a.registrar.(registry.BatchDeregistrar); ok {
+		return batch.BatchDeregister(ctx, instances...)
+	}
+	var errs []error
+	for _, si := range instances {
+		if err := a.registrar.Deregister(ctx, si); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
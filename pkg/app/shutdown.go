@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/server"
+)
+
+// Phase 优雅关闭的阶段, 按声明顺序依次执行
+type Phase string
+
+const (
+	PhaseDeregister     Phase = "deregister"      // 从注册中心注销
+	PhaseBeforeStop     Phase = "before-stop"     // BeforeStop hooks
+	PhaseDrain          Phase = "drain"           // 等待负载均衡感知实例下线
+	PhaseStopServers    Phase = "stop-servers"    // 停止所有传输服务
+	PhaseCloseResources Phase = "close-resources" // 关闭 DB、Redis 等资源
+	PhaseAfterStop      Phase = "after-stop"      // AfterStop hooks
+)
+
+const (
+	defaultStopTimeout  = 30 * time.Second
+	defaultPhaseTimeout = 10 * time.Second
+)
+
+// phaseTimeout 阶段超时时间, 未单独配置时注销阶段沿用 registryTimeout, 其余使用默认值
+func (o *options) phaseTimeout(phase Phase) time.Duration {
+	if d, ok := o.phaseTimeouts[phase]; ok {
+		return d
+	}
+	if phase == PhaseDeregister && o.registryTimeout > 0 {
+		return o.registryTimeout
+	}
+	return defaultPhaseTimeout
+}
+
+// shutdown 按阶段顺序优雅关闭应用.
+// 每个阶段的时长受 min(阶段超时, 总截止时间) 约束, 超时后继续执行下一阶段,
+// 所有阶段和 hook 的错误聚合后返回.
+func (a *App) shutdown(ctx context.Context, servers []server.Server) error {
+	ctx, cancel := context.WithTimeout(ctx, a.opts.stopTimeout)
+	defer cancel()
+
+	// 立即置为未就绪, http /readyz 返回 503, grpc 健康检查返回 NOT_SERVING
+	a.health.SetReady(false)
+
+	var errs []error
+	runPhase := func(phase Phase, fn func(ctx context.Context) error) {
+		start := time.Now()
+		pctx, pcancel := context.WithTimeout(ctx, a.opts.phaseTimeout(phase))
+		defer pcancel()
+		if err := fn(pctx); err != nil {
+			logger.Errorf("shutdown phase %s error: %v", phase, err)
+			errs = append(errs, fmt.Errorf("%s: %w", phase, err))
+			return
+		}
+		logger.Debugf("shutdown phase %s done in %s", phase, time.Since(start))
+	}
+
+	runPhase(PhaseDeregister, a.deregister)
+	runPhase(PhaseBeforeStop, func(ctx context.Context) error {
+		return runHooks(ctx, a.opts.beforeStop)
+	})
+	if a.opts.drainDelay > 0 {
+		runPhase(PhaseDrain, func(ctx context.Context) error {
+			logger.Infof("waiting %s for load balancers to drain", a.opts.drainDelay)
+			select {
+			case <-time.After(a.opts.drainDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+	runPhase(PhaseStopServers, func(ctx context.Context) error {
+		return stopServers(ctx, servers)
+	})
+	runPhase(PhaseCloseResources, a.closeResources)
+	runPhase(PhaseAfterStop, func(ctx context.Context) error {
+		return runHooks(ctx, a.opts.afterStop)
+	})
+	return errors.Join(errs...)
+}
+
+// closeResources 关闭 App 托管的 DB、Redis、etcd 连接, 上报剩余的链路追踪数据
+func (a *App) closeResources(ctx context.Context) error {
+	var errs []error
+	if a.db != nil {
+		if sqlDB, err := a.db.DB(); err != nil {
+			errs = append(errs, fmt.Errorf("db: %w", err))
+		} else if err = sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("db: %w", err))
+		}
+	}
+	if a.redis != nil {
+		if err := a.redis.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis: %w", err))
+		}
+	}
+	if a.etcdClient != nil {
+		if err := a.etcdClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("etcd: %w", err))
+		}
+	}
+	if a.releaseRpcBreaker != nil {
+		a.releaseRpcBreaker()
+	}
+	if a.tracingShutdown != nil {
+		if err := a.tracingShutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tracing: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopServers 并发停止所有传输服务, 避免单个服务耗尽整个阶段的时间
+func stopServers(ctx context.Context, servers []server.Server) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, srv := range servers {
+		srv := srv
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Stop(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// runHooks 依次执行所有 hook, 单个 hook 超出 ctx 时限后不再等待, 聚合所有错误
+func runHooks(ctx context.Context, hooks []func(context.Context) error) error {
+	var errs []error
+	for _, fn := range hooks {
+		if err := runWithContext(ctx, fn); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runWithContext(ctx context.Context, fn func(context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,151 @@
+// Package breaker 客户端熔断, 下游异常时快速失败, 避免持续请求拖垮下游.
+//  1. sre: Google SRE 自适应限流, 按 (请求数 - K × 成功数) / (请求数 + 1) 的概率拒绝请求, 随下游恢复平滑放开.
+//  2. classic: 经典三态熔断, 失败率超过阈值后打开, 超时后半开放行少量探测请求, 探测成功后关闭.
+//
+// Group 按 key (目标服务 + 方法) 创建熔断器, 通过 grpc 客户端拦截器、http 客户端中间件使用.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+)
+
+const (
+	KindSRE     = "sre"
+	KindClassic = "classic"
+)
+
+// ErrNotAllowed 熔断器拒绝请求
+var ErrNotAllowed = errors.New("breaker: circuit breaker is open")
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 正常放行
+	StateOpen                  // 拒绝请求, sre 表示正在按概率拒绝
+	StateHalfOpen              // 放行探测请求
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+type Config struct {
+	Kind             string        `yaml:"kind"`             // sre | classic, 为空时不启用
+	Window           time.Duration `yaml:"window"`           // 统计窗口, 默认 10s
+	MinRequests      int64         `yaml:"minRequests"`      // 窗口内请求数少于该值时不熔断, 默认 20
+	K                float64       `yaml:"k"`                // sre: 倍率, 越小越容易拒绝, 默认 1.5
+	FailureRatio     float64       `yaml:"failureRatio"`     // classic: 打开的失败率阈值, 默认 0.5
+	OpenTimeout      time.Duration `yaml:"openTimeout"`      // classic: 打开后多久进入半开, 默认 5s
+	HalfOpenRequests int64         `yaml:"halfOpenRequests"` // classic: 半开时的探测请求数, 全部成功后关闭, 默认 3
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.K <= 0 {
+		c.K = 1.5
+	}
+	if c.FailureRatio <= 0 || c.FailureRatio > 1 {
+		c.FailureRatio = 0.5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 5 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 3
+	}
+	return c
+}
+
+// Breaker 熔断器, 实现需并发安全.
+// 每次请求前调用 Allow, 放行的请求结束后调用 MarkSuccess 或 MarkFailed.
+type Breaker interface {
+	Allow() error
+	MarkSuccess()
+	MarkFailed()
+	State() State
+}
+
+// New 按 conf.Kind 创建熔断器, name 用于日志及指标
+func New(name string, conf Config) (Breaker, error) {
+	conf = conf.withDefaults()
+	switch conf.Kind {
+	case KindSRE:
+		return newSRE(name, conf), nil
+	case KindClassic:
+		return newClassic(name, conf), nil
+	}
+	return nil, fmt.Errorf("breaker: unknown kind %q", conf.Kind)
+}
+
+// Group 按 key 懒创建熔断器, 并发安全
+type Group struct {
+	conf Config
+
+	mu       sync.RWMutex
+	breakers map[string]Breaker
+}
+
+// NewGroup 创建熔断器组, conf.Kind 无效时返回错误
+func NewGroup(conf Config) (*Group, error) {
+	if _, err := New("", conf); err != nil {
+		return nil, err
+	}
+	return &Group{conf: conf, breakers: make(map[string]Breaker)}, nil
+}
+
+// Get 返回 key 对应的熔断器, 不存在时创建
+func (g *Group) Get(key string) Breaker {
+	g.mu.RLock()
+	b, ok := g.breakers[key]
+	g.mu.RUnlock()
+	if ok {
+		return b
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if b, ok = g.breakers[key]; ok {
+		return b
+	}
+	b, _ = New(key, g.conf) // NewGroup 已校验配置
+	g.breakers[key] = b
+	return b
+}
+
+// stateNotifier 记录状态变化的日志及指标
+type stateNotifier struct {
+	name  string
+	state State
+}
+
+// set 调用方持有锁
+func (n *stateNotifier) set(state State) {
+	if n.state == state {
+		return
+	}
+	logger.Warnf("circuit breaker %s: %s -> %s", n.name, n.state, state)
+	metrics.BreakerState(n.name, int(state))
+	n.state = state
+}
+
+// now 当前时间, 测试中替换
+var now = time.Now
@@ -0,0 +1,200 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock 替换 now, 返回推进时间的函数
+func fakeClock(t *testing.T) func(d time.Duration) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { clock = clock.Add(d) }
+}
+
+func TestClassic(t *testing.T) {
+	advance := fakeClock(t)
+	b, err := New("classic", Config{Kind: KindClassic, MinRequests: 4, OpenTimeout: time.Second, HalfOpenRequests: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.MarkSuccess()
+	b.MarkSuccess()
+	b.MarkFailed()
+	if b.State() != StateClosed {
+		t.Fatalf("state = %s, want closed below min requests", b.State())
+	}
+	b.MarkFailed()
+	if b.State() != StateOpen || !errors.Is(b.Allow(), ErrNotAllowed) {
+		t.Fatalf("state = %s, want open after 50%% failures", b.State())
+	}
+
+	// 半开只放行 HalfOpenRequests 个探测请求, 探测失败重新打开
+	advance(time.Second)
+	if b.Allow() != nil || b.State() != StateHalfOpen {
+		t.Fatalf("state = %s, want half-open", b.State())
+	}
+	b.MarkFailed()
+	if b.State() != StateOpen {
+		t.Fatalf("state = %s, want open after failed probe", b.State())
+	}
+
+	advance(time.Second)
+	if b.Allow() != nil || b.Allow() != nil || !errors.Is(b.Allow(), ErrNotAllowed) {
+		t.Fatal("want 2 probes allowed in half-open")
+	}
+	b.MarkSuccess()
+	b.MarkSuccess()
+	if b.State() != StateClosed || b.Allow() != nil {
+		t.Fatalf("state = %s, want closed after probes succeeded", b.State())
+	}
+}
+
+func TestSRE(t *testing.T) {
+	advance := fakeClock(t)
+	b, err := New("sre", Config{Kind: KindSRE, MinRequests: 10, Window: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err = b.Allow(); err == nil {
+			b.MarkSuccess()
+		}
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("state = %s, want closed when downstream is healthy", b.State())
+	}
+
+	// 窗口过期后下游持续失败, 大部分请求被拒绝
+	advance(time.Second)
+	rejected := 0
+	for i := 0; i < 200; i++ {
+		if err = b.Allow(); err != nil {
+			rejected++
+			continue
+		}
+		b.MarkFailed()
+	}
+	if b.State() != StateOpen || rejected < 150 {
+		t.Fatalf("state = %s, rejected = %d, want most rejected", b.State(), rejected)
+	}
+
+	// 失败记录过期后恢复放行
+	advance(time.Second)
+	if b.Allow() != nil || b.State() != StateClosed {
+		t.Fatalf("state = %s, want closed after window expired", b.State())
+	}
+}
+
+func TestNewGroup(t *testing.T) {
+	if _, err := NewGroup(Config{Kind: "unknown"}); err == nil {
+		t.Fatal("want error for unknown kind")
+	}
+	g, err := NewGroup(Config{Kind: KindSRE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Get("a") != g.Get("a") || g.Get("a") == g.Get("b") {
+		t.Fatal("want one breaker per key")
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	g, _ := NewGroup(Config{Kind: KindClassic, MinRequests: 2, OpenTimeout: time.Minute})
+	cc, err := grpc.Dial("passthrough:///downstream", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	interceptor := UnaryClientInterceptor(g)
+	calls := 0
+	invoke := func(code codes.Code) error {
+		return interceptor(context.Background(), "/svc/Method", nil, nil, cc,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				calls++
+				return status.Error(code, "error")
+			})
+	}
+
+	// 业务错误不计入失败
+	for i := 0; i < 3; i++ {
+		_ = invoke(codes.InvalidArgument)
+	}
+	for i := 0; i < 3; i++ {
+		_ = invoke(codes.Unavailable)
+	}
+	if err = invoke(codes.Unavailable); status.Code(err) != codes.Unavailable || calls != 6 {
+		t.Fatalf("err = %v, calls = %d, want rejected without invoking", err, calls)
+	}
+	if g.Get("passthrough:///downstream/svc/Other").Allow() != nil {
+		t.Fatal("want breaker per method")
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	advance := fakeClock(t)
+	g, _ := NewGroup(Config{Kind: KindClassic, MinRequests: 1, OpenTimeout: time.Second, HalfOpenRequests: 1})
+	cc, err := grpc.Dial("passthrough:///downstream", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	interceptor := StreamClientInterceptor(g)
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	stream := func(ctx context.Context) error {
+		_, err := interceptor(ctx, desc, cc, "/svc/Watch",
+			func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return nil, nil // 不读取的流
+			})
+		return err
+	}
+
+	b := g.Get("passthrough:///downstream/svc/Watch")
+	b.MarkFailed()
+	advance(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err = stream(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = stream(context.Background()); status.Code(err) != codes.Unavailable {
+		t.Fatalf("err = %v, want probe slot taken", err)
+	}
+	// 探测流未读完即取消, 释放探测名额
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for b.State() == StateHalfOpen && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err = stream(context.Background()); err != nil {
+		t.Fatalf("err = %v, state = %s, want breaker recovered after abandoned probe", err, b.State())
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	g, _ := NewGroup(Config{Kind: KindClassic, MinRequests: 2, OpenTimeout: time.Minute})
+	client := &http.Client{Transport: Transport(g)(http.DefaultTransport)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("err = %v, want ErrNotAllowed", err)
+	}
+}
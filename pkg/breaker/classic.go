@@ -0,0 +1,87 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+)
+
+// classic 三态熔断
+type classic struct {
+	conf Config
+
+	mu       sync.Mutex
+	window   *rollingWindow
+	openedAt time.Time
+	probes   int64 // 半开时已放行的探测请求数
+	passed   int64 // 半开时成功的探测请求数
+	notifier stateNotifier
+}
+
+func newClassic(name string, conf Config) *classic {
+	return &classic{conf: conf, window: newRollingWindow(conf.Window), notifier: stateNotifier{name: name}}
+}
+
+func (b *classic) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.notifier.state {
+	case StateOpen:
+		if now().Sub(b.openedAt) < b.conf.OpenTimeout {
+			metrics.BreakerRejected(b.notifier.name)
+			return ErrNotAllowed
+		}
+		b.probes, b.passed = 0, 0
+		b.notifier.set(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.conf.HalfOpenRequests {
+			metrics.BreakerRejected(b.notifier.name)
+			return ErrNotAllowed
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *classic) MarkSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.notifier.state {
+	case StateClosed:
+		b.window.add(true)
+	case StateHalfOpen:
+		b.passed++
+		if b.passed >= b.conf.HalfOpenRequests {
+			b.window.reset()
+			b.notifier.set(StateClosed)
+		}
+	}
+}
+
+func (b *classic) MarkFailed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.notifier.state {
+	case StateClosed:
+		b.window.add(false)
+		total, success := b.window.sum()
+		if total >= b.conf.MinRequests && float64(total-success)/float64(total) >= b.conf.FailureRatio {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.open()
+	}
+}
+
+func (b *classic) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.notifier.state
+}
+
+func (b *classic) open() {
+	b.openedAt = now()
+	b.notifier.set(StateOpen)
+}
@@ -0,0 +1,68 @@
+package breaker
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+)
+
+// sre 自适应限流, 见 https://sre.google/sre-book/handling-overload/
+type sre struct {
+	conf Config
+
+	mu       sync.Mutex
+	window   *rollingWindow
+	rand     *rand.Rand
+	notifier stateNotifier
+}
+
+func newSRE(name string, conf Config) *sre {
+	return &sre{
+		conf:     conf,
+		window:   newRollingWindow(conf.Window),
+		rand:     rand.New(rand.NewSource(now().UnixNano())),
+		notifier: stateNotifier{name: name},
+	}
+}
+
+func (b *sre) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total, success := b.window.sum()
+	p := 0.0
+	if total >= b.conf.MinRequests {
+		p = math.Max(0, (float64(total)-b.conf.K*float64(success))/float64(total+1))
+	}
+	if p <= 0 {
+		b.notifier.set(StateClosed)
+		return nil
+	}
+	b.notifier.set(StateOpen)
+	if b.rand.Float64() < p {
+		// 被拒绝的请求同样计入请求数, 下游持续异常时拒绝概率继续上升
+		b.window.add(false)
+		metrics.BreakerRejected(b.notifier.name)
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+func (b *sre) MarkSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window.add(true)
+}
+
+func (b *sre) MarkFailed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window.add(false)
+}
+
+func (b *sre) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.notifier.state
+}
@@ -0,0 +1,123 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor 按 目标服务 + 方法 熔断, 被拒绝时返回 codes.Unavailable
+func UnaryClientInterceptor(g *Group) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		b := g.Get(cc.Target() + method)
+		if err := b.Allow(); err != nil {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		mark(b, grpcFailed(err))
+		return err
+	}
+}
+
+// StreamClientInterceptor 按 目标服务 + 方法 熔断, 流结束时按最终状态记录结果
+func StreamClientInterceptor(g *Group) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		b := g.Get(cc.Target() + method)
+		if err := b.Allow(); err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			mark(b, grpcFailed(err))
+			return nil, err
+		}
+		stream := &clientStream{ClientStream: cs, breaker: b, serverStreams: desc.ServerStreams}
+		// 调用方未读完就取消或超时的流同样记录结果, 避免半开时的探测名额一直被占用
+		stream.stop = context.AfterFunc(ctx, func() {
+			stream.finish(status.FromContextError(ctx.Err()).Err())
+		})
+		return stream, nil
+	}
+}
+
+// Transport http 客户端中间件, 按 实例地址 + http 方法 熔断, 网络错误及 5xx 视为失败,
+// 可用于 client/http.WithMiddleware
+func Transport(g *Group) func(next http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripper(func(req *http.Request) (*http.Response, error) {
+			b := g.Get(req.URL.Host + " " + req.Method)
+			if err := b.Allow(); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Host, err)
+			}
+			resp, err := next.RoundTrip(req)
+			mark(b, httpFailed(resp, err))
+			return resp, err
+		})
+	}
+}
+
+func mark(b Breaker, failed bool) {
+	if failed {
+		b.MarkFailed()
+		return
+	}
+	b.MarkSuccess()
+}
+
+// grpcFailed 下游异常导致的错误, 参数错误等业务错误不计入失败
+func grpcFailed(err error) bool {
+	switch status.Code(err) {
+	case codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal,
+		codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+func httpFailed(resp *http.Response, err error) bool {
+	if err != nil {
+		// 调用方取消不代表下游异常
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+type roundTripper func(req *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	breaker       Breaker
+	serverStreams bool
+	once          sync.Once
+	stop          func() bool // 取消 ctx 结束时的记录
+}
+
+// RecvMsg 服务端流在收到 io.EOF 或错误时结束, 客户端流收到响应即结束
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.finish(err)
+		s.stop()
+	}
+	return err
+}
+
+// finish 记录流的结果, 只记录一次
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		mark(s.breaker, err != nil && !errors.Is(err, io.EOF) && grpcFailed(err))
+	})
+}
@@ -0,0 +1,59 @@
+package breaker
+
+import "time"
+
+// buckets 统计窗口的桶数
+const buckets = 10
+
+// rollingWindow 滑动窗口计数, 调用方持有锁
+type rollingWindow struct {
+	interval time.Duration // 每个桶的时长
+	buckets  [buckets]bucket
+	offset   int       // 当前桶
+	last     time.Time // 当前桶的开始时间
+}
+
+type bucket struct {
+	total   int64
+	success int64
+}
+
+func newRollingWindow(window time.Duration) *rollingWindow {
+	return &rollingWindow{interval: window / buckets, last: now()}
+}
+
+func (w *rollingWindow) add(success bool) {
+	w.advance()
+	w.buckets[w.offset].total++
+	if success {
+		w.buckets[w.offset].success++
+	}
+}
+
+// sum 窗口内的请求数、成功数
+func (w *rollingWindow) sum() (total, success int64) {
+	w.advance()
+	for _, b := range w.buckets {
+		total += b.total
+		success += b.success
+	}
+	return total, success
+}
+
+func (w *rollingWindow) reset() {
+	w.buckets = [buckets]bucket{}
+	w.last = now()
+}
+
+// advance 清空已过期的桶
+func (w *rollingWindow) advance() {
+	n := int(now().Sub(w.last) / w.interval)
+	if n <= 0 {
+		return
+	}
+	for i := 0; i < n && i < buckets; i++ {
+		w.offset = (w.offset + 1) % buckets
+		w.buckets[w.offset] = bucket{}
+	}
+	w.last = w.last.Add(time.Duration(n) * w.interval)
+}
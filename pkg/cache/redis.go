@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
 	"github.com/redis/go-redis/v9"
 	"time"
 )
@@ -41,11 +43,15 @@ func NewRedis(cfg RedisConf) (redis.UniversalClient, error) {
 		})
 	}
 
+	rdb.AddHook(tracing.NewRedisHook())
+	rdb.AddHook(metrics.NewRedisHook())
+
 	var err error
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 	err = rdb.Ping(ctx).Err()
 	if err != nil {
+		_ = rdb.Close()
 		return nil, fmt.Errorf("redis ping %w", err)
 	}
 
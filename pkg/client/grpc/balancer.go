@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/gogoclouds/project-layout/pkg/selector"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 基于 selector 的负载均衡策略名, 通过 WithBalancer 使用
+const (
+	BalancerWeightedRoundRobin = "selector_wrr"
+	BalancerP2C                = "selector_p2c"
+	BalancerConsistentHash     = "selector_consistent_hash" // key 见 selector.WithHashKey
+)
+
+func init() {
+	RegisterBalancer(BalancerWeightedRoundRobin, selector.NewWeightedRoundRobin)
+	RegisterBalancer(BalancerP2C, selector.NewP2C)
+	RegisterBalancer(BalancerConsistentHash, selector.NewConsistentHash)
+}
+
+// RegisterBalancer 注册基于 selector 的负载均衡策略, 每个连接使用独立的 Balancer 实例.
+// filters 可以按请求 ctx 筛选节点, 如 selector.Version、selector.Env; 需在 Dial 之前调用.
+func RegisterBalancer(name string, newBalancer func() selector.Balancer, filters ...selector.Filter) {
+	balancer.Register(&balancerBuilder{name: name, newBalancer: newBalancer, filters: filters})
+}
+
+type balancerBuilder struct {
+	name        string
+	newBalancer func() selector.Balancer
+	filters     []selector.Filter
+}
+
+func (b *balancerBuilder) Name() string {
+	return b.name
+}
+
+func (b *balancerBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &pickerBuilder{selector: selector.New(b.newBalancer(), b.filters...)}
+	return base.NewBalancerBuilder(b.name, pb, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+type pickerBuilder struct {
+	selector *selector.Selector
+}
+
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &picker{selector: b.selector, subConns: make(map[string]balancer.SubConn, len(info.ReadySCs))}
+	for sc, sci := range info.ReadySCs {
+		ins, _ := InstanceFromAddress(sci.Address)
+		p.nodes = append(p.nodes, &selector.Node{Address: sci.Address.Addr, Instance: ins})
+		p.subConns[sci.Address.Addr] = sc
+	}
+	sort.Slice(p.nodes, func(i, j int) bool { return p.nodes[i].Address < p.nodes[j].Address })
+	return p
+}
+
+type picker struct {
+	selector *selector.Selector
+	nodes    []*selector.Node
+	subConns map[string]balancer.SubConn
+}
+
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	n, done, err := p.selector.Select(info.Ctx, p.nodes)
+	if err != nil {
+		if errors.Is(err, selector.ErrNoAvailable) {
+			return balancer.PickResult{}, status.Error(codes.Unavailable, err.Error())
+		}
+		return balancer.PickResult{}, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return balancer.PickResult{
+		SubConn: p.subConns[n.Address],
+		Done: func(di balancer.DoneInfo) {
+			done(info.Ctx, selector.DoneInfo{Err: di.Err})
+		},
+	}, nil
+}
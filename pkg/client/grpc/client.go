@@ -0,0 +1,146 @@
+// Package grpc grpc 客户端: 基于服务发现的 resolver 及并发安全的连接缓存.
+//
+//	pool := grpc.NewPool(grpc.WithDiscovery(app.Discovery()))
+//	conn, err := pool.Get(ctx, "discovery:///user-service")
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/requestid"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type Option func(o *options)
+
+type options struct {
+	discovery   registry.ServiceDiscovery
+	balancer    string
+	creds       credentials.TransportCredentials
+	dialOptions []grpc.DialOption
+}
+
+// WithDiscovery 解析 discovery:///<service> 地址使用的服务发现
+func WithDiscovery(d registry.ServiceDiscovery) Option {
+	return func(o *options) {
+		o.discovery = d
+	}
+}
+
+// WithBalancer 负载均衡策略名, 默认 round_robin, 可选 BalancerWeightedRoundRobin 等 selector 策略
+func WithBalancer(name string) Option {
+	return func(o *options) {
+		o.balancer = name
+	}
+}
+
+// WithTransportCredentials 传输层凭证, 默认不加密
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(o *options) {
+		o.creds = creds
+	}
+}
+
+// WithDialOptions 追加 grpc.DialOption, 如自定义拦截器
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// WithBreaker 按 目标服务 + 方法 熔断, 见 breaker.UnaryClientInterceptor
+func WithBreaker(g *breaker.Group) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions,
+			grpc.WithChainUnaryInterceptor(breaker.UnaryClientInterceptor(g)),
+			grpc.WithChainStreamInterceptor(breaker.StreamClientInterceptor(g)))
+	}
+}
+
+// Dial 创建连接, 默认携带链路追踪、请求 ID 拦截器; 不阻塞等待连接建立
+func Dial(ctx context.Context, target string, opts ...Option) (*grpc.ClientConn, error) {
+	o := options{balancer: roundrobin.Name, creds: insecure.NewCredentials()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(o.creds),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]}`, o.balancer)),
+		tracing.DialOption(),
+		grpc.WithChainUnaryInterceptor(requestid.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(requestid.StreamClientInterceptor()),
+	}
+	if o.discovery != nil {
+		dialOpts = append(dialOpts, grpc.WithResolvers(NewBuilder(o.discovery)))
+	}
+	dialOpts = append(dialOpts, o.dialOptions...)
+	return grpc.DialContext(ctx, target, dialOpts...)
+}
+
+// Pool 按 target 缓存连接, 并发安全. 已关闭的连接在下次 Get 时重建.
+type Pool struct {
+	opts []Option
+
+	mu     sync.RWMutex
+	conns  map[string]*grpc.ClientConn
+	closed bool
+}
+
+// NewPool 创建连接缓存, opts 用于所有连接
+func NewPool(opts ...Option) *Pool {
+	return &Pool{opts: opts, conns: make(map[string]*grpc.ClientConn)}
+}
+
+// Get 获取 target 的连接, 不存在时创建
+func (p *Pool) Get(ctx context.Context, target string) (*grpc.ClientConn, error) {
+	p.mu.RLock()
+	cc, ok := p.conns[target]
+	closed := p.closed
+	p.mu.RUnlock()
+	if closed {
+		return nil, errors.New("grpc pool closed")
+	}
+	if ok && cc.GetState() != connectivity.Shutdown {
+		return cc, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errors.New("grpc pool closed")
+	}
+	if cc, ok = p.conns[target]; ok && cc.GetState() != connectivity.Shutdown {
+		return cc, nil
+	}
+	cc, err := Dial(ctx, target, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[target] = cc
+	return cc, nil
+}
+
+// Close 关闭所有连接, 之后 Get 返回错误
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var errs []error
+	for target, cc := range p.conns {
+		if err := cc.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", target, err))
+		}
+	}
+	p.conns = make(map[string]*grpc.ClientConn)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,180 @@
+package grpc_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/api/admin/v1/helloworld"
+	clientgrpc "github.com/gogoclouds/project-layout/pkg/client/grpc"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/selector"
+	"google.golang.org/grpc"
+)
+
+// memoryDiscovery 通过 push 推送实例变化
+type memoryDiscovery struct {
+	updates chan []*registry.ServiceInstance
+}
+
+func (d *memoryDiscovery) GetService(context.Context, string) ([]*registry.ServiceInstance, error) {
+	return nil, nil
+}
+
+func (d *memoryDiscovery) Watch(ctx context.Context, _ string) (registry.Watcher, error) {
+	return &memoryWatcher{ctx: ctx, updates: d.updates}, nil
+}
+
+type memoryWatcher struct {
+	ctx     context.Context
+	updates chan []*registry.ServiceInstance
+}
+
+func (w *memoryWatcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case ins := <-w.updates:
+		return ins, nil
+	}
+}
+
+func (w *memoryWatcher) Stop() error { return nil }
+
+type greeter struct {
+	helloworld.UnimplementedGreeterServer
+	name string
+}
+
+func (g *greeter) SayHello(context.Context, *helloworld.HelloRequest) (*helloworld.HelloReply, error) {
+	return &helloworld.HelloReply{Message: g.name}, nil
+}
+
+func startGreeter(t *testing.T, name string) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	helloworld.RegisterGreeterServer(srv, &greeter{name: name})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestDiscoveryResolver(t *testing.T) {
+	a, b := startGreeter(t, "a"), startGreeter(t, "b")
+	d := &memoryDiscovery{updates: make(chan []*registry.ServiceInstance, 1)}
+	d.updates <- []*registry.ServiceInstance{
+		{ID: "1", Name: "greeter", Endpoints: []string{"http://127.0.0.1:1", "grpc://" + a}},
+		{ID: "2", Name: "greeter", Endpoints: []string{"grpc://" + b}},
+		{ID: "3", Name: "greeter", Endpoints: []string{"grpc://127.0.0.1:1"},
+			Metadata: map[string]string{registry.MetadataDraining: "true"}},
+	}
+
+	pool := clientgrpc.NewPool(clientgrpc.WithDiscovery(d))
+	defer pool.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := pool.Get(ctx, "discovery:///greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := helloworld.NewGreeterClient(conn)
+	hits := func(n int) map[string]int {
+		got := make(map[string]int)
+		for i := 0; i < n; i++ {
+			reply, err := client.SayHello(ctx, &helloworld.HelloRequest{}, grpc.WaitForReady(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[reply.Message]++
+		}
+		return got
+	}
+	// round_robin 建立子连接后请求均匀分布在 a、b 上
+	deadline := time.Now().Add(3 * time.Second)
+	for got := hits(4); got["a"] != 2 || got["b"] != 2; got = hits(4) {
+		if time.Now().After(deadline) {
+			t.Fatalf("hits = %v, want a and b evenly", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 实例变化推送后只访问 b
+	d.updates <- []*registry.ServiceInstance{{ID: "2", Name: "greeter", Endpoints: []string{"grpc://" + b}}}
+	deadline = time.Now().Add(3 * time.Second)
+	for got := hits(4); got["b"] != 4; got = hits(4) {
+		if time.Now().After(deadline) {
+			t.Fatalf("hits = %v, want only b", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolConcurrentGet(t *testing.T) {
+	pool := clientgrpc.NewPool()
+	var wg sync.WaitGroup
+	conns := make([]*grpc.ClientConn, 8)
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i], _ = pool.Get(context.Background(), "127.0.0.1:1")
+		}(i)
+	}
+	wg.Wait()
+	for _, cc := range conns {
+		if cc == nil || cc != conns[0] {
+			t.Fatal("want the same cached connection")
+		}
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Get(context.Background(), "127.0.0.1:1"); err == nil {
+		t.Fatal("want error after close")
+	}
+}
+
+func TestSelectorBalancer(t *testing.T) {
+	a, b := startGreeter(t, "a"), startGreeter(t, "b")
+	d := &memoryDiscovery{updates: make(chan []*registry.ServiceInstance, 1)}
+	d.updates <- []*registry.ServiceInstance{
+		{ID: "1", Name: "greeter", Endpoints: []string{"grpc://" + a}},
+		{ID: "2", Name: "greeter", Endpoints: []string{"grpc://" + b}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := clientgrpc.Dial(ctx, "discovery:///greeter",
+		clientgrpc.WithDiscovery(d), clientgrpc.WithBalancer(clientgrpc.BalancerConsistentHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := helloworld.NewGreeterClient(conn)
+	call := func(key string) string {
+		reply, err := client.SayHello(selector.WithHashKey(ctx, key), &helloworld.HelloRequest{}, grpc.WaitForReady(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reply.Message
+	}
+	// 等待两个子连接就绪
+	seen := make(map[string]bool)
+	for i := 0; len(seen) < 2; i++ {
+		if ctx.Err() != nil {
+			t.Fatal("want both servers picked")
+		}
+		seen[call(fmt.Sprintf("user-%d", i))] = true
+	}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		if first := call(key); call(key) != first {
+			t.Fatalf("key %s picked different servers", key)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/selector"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// Scheme 服务发现地址前缀, 如 discovery:///user-service
+	Scheme = "discovery"
+
+	// endpointScheme 实例 Endpoints 中 grpc 地址的 scheme, 同 server.KindGRPC
+	endpointScheme = "grpc"
+
+	retryInterval = time.Second
+)
+
+type instanceKey struct{}
+
+// InstanceFromAddress 取出地址对应的服务实例, 供负载均衡按实例元数据选择
+func InstanceFromAddress(addr resolver.Address) (*registry.ServiceInstance, bool) {
+	ins, ok := addr.Attributes.Value(instanceKey{}).(*registry.ServiceInstance)
+	return ins, ok
+}
+
+// NewBuilder 基于服务发现的 grpc resolver, 订阅 discovery:///<service> 的实例变化并推送给负载均衡.
+// 只使用 grpc:// 地址, 跳过正在下线 (registry.MetadataDraining) 的实例.
+func NewBuilder(discovery registry.ServiceDiscovery) resolver.Builder {
+	return &builder{discovery: discovery}
+}
+
+type builder struct {
+	discovery registry.ServiceDiscovery
+}
+
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	name := target.Endpoint()
+	if name == "" {
+		return nil, fmt.Errorf("grpc resolver: empty service name in %q", target.URL.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := b.discovery.Watch(ctx, name)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("grpc resolver: watch %s: %w", name, err)
+	}
+	r := &discoveryResolver{
+		name:    name,
+		watcher: w,
+		cc:      cc,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go r.watch()
+	return r, nil
+}
+
+type discoveryResolver struct {
+	name    string
+	watcher registry.Watcher
+	cc      resolver.ClientConn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ResolveNow 实例变化由 Watcher 推送, 无需主动解析
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *discoveryResolver) Close() {
+	r.cancel()
+	if err := r.watcher.Stop(); err != nil {
+		logger.Errorf("grpc resolver: stop watcher %s: %v", r.name, err)
+	}
+	<-r.done
+}
+
+func (r *discoveryResolver) watch() {
+	defer close(r.done)
+	for {
+		instances, err := r.watcher.Next()
+		if r.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Errorf("grpc resolver: watch %s: %v", r.name, err)
+			r.cc.ReportError(err)
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+			continue
+		}
+		r.update(instances)
+	}
+}
+
+func (r *discoveryResolver) update(instances []*registry.ServiceInstance) {
+	addrs := Addresses(instances)
+	if len(addrs) == 0 {
+		logger.Warnf("grpc resolver: no available grpc endpoint for %s", r.name)
+		r.cc.ReportError(fmt.Errorf("no available grpc endpoint for %s", r.name))
+		return
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		logger.Errorf("grpc resolver: update state %s: %v", r.name, err)
+	}
+}
+
+// Addresses 将实例转换为 grpc 地址, 规则同 selector.Nodes
+func Addresses(instances []*registry.ServiceInstance) []resolver.Address {
+	nodes := selector.Nodes(instances, endpointScheme)
+	addrs := make([]resolver.Address, 0, len(nodes))
+	for _, n := range nodes {
+		addrs = append(addrs, resolver.Address{
+			Addr:       n.Address,
+			ServerName: n.Instance.Name,
+			Attributes: attributes.New(instanceKey{}, n.Instance),
+		})
+	}
+	return addrs
+}
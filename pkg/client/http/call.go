@@ -0,0 +1,119 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gogoclouds/gogo/web/r"
+)
+
+// maxErrorBody 非 r.Resp 格式的错误响应最多保留的字节数
+const maxErrorBody = 512
+
+// Error 非 2xx 响应或业务状态码不为 r.Ok
+type Error struct {
+	StatusCode int          // http 状态码
+	Code       r.StatusCode // 业务状态码, 响应不是 r.Resp 格式时为 r.Internal
+	Msg        string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("http %d, code %d: %s", e.StatusCode, e.Code, e.Msg)
+}
+
+type CallOption func(o *callOptions)
+
+type callOptions struct {
+	timeout time.Duration
+	header  http.Header
+}
+
+// Timeout 本次调用的超时时间, 覆盖 WithTimeout
+func Timeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = d
+	}
+}
+
+// Header 本次调用的请求头
+func Header(key, value string) CallOption {
+	return func(o *callOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// Invoke 以 JSON 发送 body (nil 时不发送), 解析 r.Resp 响应, 将 data 写入 reply (nil 时忽略).
+// 非 2xx 响应或业务状态码不为 r.Ok 时返回 *Error.
+func (c *Client) Invoke(ctx context.Context, method, url string, body, reply any, opts ...CallOption) error {
+	o := callOptions{header: make(http.Header)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("http client: encode request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range o.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, reply)
+}
+
+func decode(resp *http.Response, reply any) error {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var envelope struct {
+		r.Resp
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(data, &envelope); err != nil {
+		if resp.StatusCode >= http.StatusBadRequest {
+			if len(data) > maxErrorBody {
+				data = data[:maxErrorBody]
+			}
+			return &Error{StatusCode: resp.StatusCode, Code: r.Internal, Msg: string(data)}
+		}
+		return fmt.Errorf("http client: decode response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest || envelope.Code != r.Ok {
+		return &Error{StatusCode: resp.StatusCode, Code: envelope.Code, Msg: envelope.Msg}
+	}
+	if reply == nil || len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil
+	}
+	if err = json.Unmarshal(envelope.Data, reply); err != nil {
+		return fmt.Errorf("http client: decode data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package http http 客户端: 通过服务发现解析 discovery:///<service>/<path>, 由 selector 选择实例,
+// 支持调用超时、幂等请求重试, 并向下游传递请求 ID 及链路信息.
+//
+//	client := http.NewClient(http.WithDiscovery(app.Discovery()))
+//	var user User
+//	err := client.Invoke(ctx, "GET", "discovery:///user-service/v1/users/1", nil, &user)
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/requestid"
+	"github.com/gogoclouds/project-layout/pkg/selector"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
+)
+
+// Scheme 服务发现地址前缀, 如 discovery:///user-service/v1/users
+const Scheme = "discovery"
+
+// Middleware 包装 http.RoundTripper, 每次尝试 (含重试) 都会经过, 请求地址已解析为实例地址
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+type Option func(o *options)
+
+type options struct {
+	discovery  registry.ServiceDiscovery
+	selector   *selector.Selector
+	transport  http.RoundTripper
+	timeout    time.Duration
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	middleware []Middleware
+}
+
+// WithDiscovery 解析 discovery:/// 地址使用的服务发现
+func WithDiscovery(d registry.ServiceDiscovery) Option {
+	return func(o *options) {
+		o.discovery = d
+	}
+}
+
+// WithSelector 实例选择策略, 默认加权轮询
+func WithSelector(s *selector.Selector) Option {
+	return func(o *options) {
+		o.selector = s
+	}
+}
+
+// WithTransport 底层 RoundTripper, 默认 http.DefaultTransport
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithTimeout 单次调用 (含重试) 的超时时间, 默认 5s, <= 0 不限制
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetry 幂等请求 (GET、HEAD、OPTIONS、PUT、DELETE) 在网络错误或 502、503、504 时的最大重试次数
+// 及指数退避区间, 默认 2 次, 100ms ~ 1s
+func WithRetry(max int, minBackoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.maxRetries, o.minBackoff, o.maxBackoff = max, minBackoff, maxBackoff
+	}
+}
+
+// WithMiddleware 追加 RoundTripper 中间件, 先添加的在外层
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithBreaker 按 实例地址 + http 方法 熔断, 见 breaker.Transport
+func WithBreaker(g *breaker.Group) Option {
+	return WithMiddleware(breaker.Transport(g))
+}
+
+// Client http 客户端, 并发安全
+type Client struct {
+	opts     options
+	client   *http.Client
+	resolver *resolver
+}
+
+func NewClient(opts ...Option) *Client {
+	o := options{
+		transport:  http.DefaultTransport,
+		timeout:    5 * time.Second,
+		maxRetries: 2,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.selector == nil {
+		o.selector = selector.New(selector.NewWeightedRoundRobin())
+	}
+	rt := o.transport
+	for i := len(o.middleware) - 1; i >= 0; i-- {
+		rt = o.middleware[i](rt)
+	}
+	c := &Client{opts: o, client: &http.Client{Transport: tracing.Transport(requestid.Transport(rt))}}
+	if o.discovery != nil {
+		c.resolver = newResolver(o.discovery)
+	}
+	return c
+}
+
+// Do 发送请求, discovery:/// 地址每次尝试重新选择实例.
+// 超时由 WithTimeout 控制, 返回的 Body 读取完成前超时仍然生效.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && c.opts.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+	}
+	resp, err := c.do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attempts := 1
+	if idempotent(req.Method) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil) {
+		attempts += c.opts.maxRetries
+	}
+	for i := 0; ; i++ {
+		r := req.Clone(ctx)
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		resp, err := c.attempt(r)
+		if i == attempts-1 || !retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		if !sleep(ctx, c.backoff(i)) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt 解析 discovery:/// 地址并发送一次请求
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != Scheme {
+		return c.client.Do(req)
+	}
+	if c.resolver == nil {
+		return nil, errors.New("http client: discovery is required for " + req.URL.String())
+	}
+	name, rawPath := serviceName(req.URL.Host, req.URL.EscapedPath())
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := c.resolver.nodes(req.Context(), name)
+	if err != nil {
+		return nil, err
+	}
+	node, done, err := c.opts.selector.Select(req.Context(), nodes)
+	if err != nil {
+		return nil, fmt.Errorf("http client: %s: %w", name, err)
+	}
+	req.URL.Scheme, req.URL.Host, req.Host = endpointScheme, node.Address, ""
+	req.URL.Path, req.URL.RawPath = path, rawPath
+	resp, err := c.client.Do(req)
+	doneErr := err
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		doneErr = errors.New(resp.Status)
+	}
+	done(req.Context(), selector.DoneInfo{Err: doneErr})
+	return resp, err
+}
+
+// Close 停止服务发现订阅
+func (c *Client) Close() error {
+	if c.resolver != nil {
+		c.resolver.close()
+	}
+	return nil
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.opts.minBackoff << attempt
+	if d > c.opts.maxBackoff || d <= 0 {
+		d = c.opts.maxBackoff
+	}
+	return d
+}
+
+// serviceName discovery:///name/path 取出服务名及请求路径, 也支持 discovery://name/path
+func serviceName(host, path string) (string, string) {
+	if host != "" {
+		return host, path
+	}
+	name, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return name, "/" + rest
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable 只重试网络错误 (建连、读写失败) 及网关类状态码; 熔断拒绝、服务发现及配置错误不重试
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) || errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) || errors.Is(err, breaker.ErrNotAllowed) {
+			return false
+		}
+		var netErr net.Error
+		return errors.As(urlErr.Err, &netErr) || errors.Is(urlErr.Err, io.EOF) || errors.Is(urlErr.Err, io.ErrUnexpectedEOF)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// cancelBody 关闭 Body 时释放超时 ctx
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
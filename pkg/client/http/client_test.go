@@ -0,0 +1,183 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/gogo/web/r"
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	clienthttp "github.com/gogoclouds/project-layout/pkg/client/http"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/requestid"
+	"github.com/gogoclouds/project-layout/pkg/selector"
+)
+
+type staticDiscovery struct {
+	instances []*registry.ServiceInstance
+}
+
+func (d *staticDiscovery) GetService(context.Context, string) ([]*registry.ServiceInstance, error) {
+	return d.instances, nil
+}
+
+func (d *staticDiscovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	w := &staticWatcher{ctx: ctx}
+	for _, ins := range d.instances {
+		if ins.Name == name {
+			w.instances = append(w.instances, ins)
+		}
+	}
+	return w, nil
+}
+
+type staticWatcher struct {
+	ctx       context.Context
+	instances []*registry.ServiceInstance
+	sent      bool
+}
+
+func (w *staticWatcher) Next() ([]*registry.ServiceInstance, error) {
+	if !w.sent {
+		w.sent = true
+		return w.instances, nil
+	}
+	<-w.ctx.Done()
+	return nil, w.ctx.Err()
+}
+
+func (w *staticWatcher) Stop() error { return nil }
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func newServer(t *testing.T, unavailable *atomic.Int32) string {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/v1/users/:id", func(c *gin.Context) {
+		if unavailable.Add(-1) >= 0 {
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		c.JSON(http.StatusOK, r.SuccessData(user{ID: c.Param("id"), Name: c.GetHeader(requestid.Header)}))
+	})
+	e.POST("/v1/users", func(c *gin.Context) {
+		unavailable.Add(-1)
+		c.Status(http.StatusServiceUnavailable)
+	})
+	e.DELETE("/v1/users/:id", func(c *gin.Context) {
+		c.JSON(http.StatusForbidden, r.FailCode(r.Forbidden))
+	})
+	e.GET("/slow", func(c *gin.Context) {
+		time.Sleep(200 * time.Millisecond)
+		c.JSON(http.StatusOK, r.Success())
+	})
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestClientInvoke(t *testing.T) {
+	var unavailable atomic.Int32
+	addr := newServer(t, &unavailable)
+	d := &staticDiscovery{instances: []*registry.ServiceInstance{
+		{ID: "1", Name: "user", Endpoints: []string{"grpc://127.0.0.1:1", addr}},
+	}}
+	client := clienthttp.NewClient(clienthttp.WithDiscovery(d),
+		clienthttp.WithRetry(2, time.Millisecond, 10*time.Millisecond))
+	defer client.Close()
+	ctx := logger.WithRequestID(context.Background(), "req-1")
+
+	// 503 后重试成功, 请求 ID 传给下游
+	unavailable.Store(2)
+	var got user
+	if err := client.Invoke(ctx, http.MethodGet, "discovery:///user/v1/users/42", nil, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "42" || got.Name != "req-1" {
+		t.Fatalf("reply = %+v", got)
+	}
+
+	// 非幂等请求不重试
+	unavailable.Store(10)
+	err := client.Invoke(ctx, http.MethodPost, "discovery:///user/v1/users", user{Name: "n"}, nil)
+	var httpErr *clienthttp.Error
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable || unavailable.Load() != 9 {
+		t.Fatalf("err = %v, remaining = %d, want single 503", err, unavailable.Load())
+	}
+
+	// 业务错误码
+	err = client.Invoke(ctx, http.MethodDelete, "discovery:///user/v1/users/42", nil, nil)
+	if !errors.As(err, &httpErr) || httpErr.Code != r.Forbidden {
+		t.Fatalf("err = %v, want code %d", err, r.Forbidden)
+	}
+
+	// 调用超时
+	err = client.Invoke(ctx, http.MethodGet, "discovery:///user/slow", nil, nil, clienthttp.Timeout(50*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+
+	// 没有 http 地址的服务
+	err = client.Invoke(ctx, http.MethodGet, "discovery:///unknown/v1", nil, nil)
+	if !errors.Is(err, selector.ErrNoAvailable) {
+		t.Fatalf("err = %v, want ErrNoAvailable", err)
+	}
+}
+
+func TestClientRetryable(t *testing.T) {
+	var attempts atomic.Int32
+	count := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			attempts.Add(1)
+			return next.RoundTrip(req)
+		})
+	}
+	reject := func(http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, breaker.ErrNotAllowed
+		})
+	}
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		mw       []clienthttp.Middleware
+		url      string
+		wantErr  error
+		attempts int32
+	}{
+		// 127.0.0.1:1 拒绝连接
+		{name: "network error", mw: []clienthttp.Middleware{count}, url: "http://127.0.0.1:1/", attempts: 3},
+		{name: "breaker rejected", mw: []clienthttp.Middleware{count, reject}, url: "http://127.0.0.1:1/",
+			wantErr: breaker.ErrNotAllowed, attempts: 1},
+		{name: "no discovery", mw: []clienthttp.Middleware{count}, url: "discovery:///user/v1", attempts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts.Store(0)
+			client := clienthttp.NewClient(clienthttp.WithMiddleware(tt.mw...),
+				clienthttp.WithRetry(2, time.Millisecond, time.Millisecond))
+			defer client.Close()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, tt.url, nil)
+			_, err := client.Do(req)
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if attempts.Load() != tt.attempts {
+				t.Fatalf("attempts = %d, want %d", attempts.Load(), tt.attempts)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
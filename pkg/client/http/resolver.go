@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/registry"
+	"github.com/gogoclouds/project-layout/pkg/selector"
+)
+
+const (
+	// endpointScheme 实例 Endpoints 中 http 地址的 scheme, 同 server.KindHTTP
+	endpointScheme = "http"
+
+	retryInterval = time.Second
+)
+
+// resolver 按服务名订阅实例变化, 缓存 http:// 节点
+type resolver struct {
+	discovery registry.ServiceDiscovery
+
+	mu       sync.Mutex
+	services map[string]*service
+	closed   bool
+}
+
+func newResolver(d registry.ServiceDiscovery) *resolver {
+	return &resolver{discovery: d, services: make(map[string]*service)}
+}
+
+// nodes 服务的可用节点, 首次访问时订阅并等待第一次结果
+func (r *resolver) nodes(ctx context.Context, name string) ([]*selector.Node, error) {
+	s, err := r.service(name)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ready:
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nodes, s.err
+}
+
+func (r *resolver) service(name string) (*service, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, fmt.Errorf("http client: closed")
+	}
+	if s, ok := r.services[name]; ok {
+		return s, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := r.discovery.Watch(ctx, name)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("http client: watch %s: %w", name, err)
+	}
+	s := &service{name: name, watcher: w, ctx: ctx, cancel: cancel, ready: make(chan struct{}), done: make(chan struct{})}
+	r.services[name] = s
+	go s.watch()
+	return s, nil
+}
+
+func (r *resolver) close() {
+	r.mu.Lock()
+	services := r.services
+	r.services, r.closed = nil, true
+	r.mu.Unlock()
+	for _, s := range services {
+		s.stop()
+	}
+}
+
+type service struct {
+	name    string
+	watcher registry.Watcher
+	ctx     context.Context
+	cancel  context.CancelFunc
+	ready   chan struct{} // 收到第一次结果后关闭
+	done    chan struct{}
+
+	mu    sync.RWMutex
+	nodes []*selector.Node
+	err   error
+}
+
+func (s *service) watch() {
+	defer close(s.done)
+	first := true
+	for {
+		instances, err := s.watcher.Next()
+		if s.ctx.Err() != nil {
+			if first {
+				close(s.ready)
+			}
+			return
+		}
+		s.mu.Lock()
+		if err != nil {
+			logger.Errorf("http client: watch %s: %v", s.name, err)
+			// 保留上一次的节点, 只在没有结果时返回错误
+			if first {
+				s.err = err
+			}
+		} else {
+			s.nodes, s.err = selector.Nodes(instances, endpointScheme), nil
+		}
+		s.mu.Unlock()
+		if first {
+			close(s.ready)
+			first = false
+		}
+		if err != nil {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+	}
+}
+
+func (s *service) stop() {
+	s.cancel()
+	if err := s.watcher.Stop(); err != nil {
+		logger.Errorf("http client: stop watcher %s: %v", s.name, err)
+	}
+	<-s.done
+}
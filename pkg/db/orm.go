@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"fmt"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
@@ -33,12 +35,22 @@ func NewDB(dialector gorm.Dialector, conf Config) (*gorm.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get DB err: %w", err)
 	}
+	// 后续步骤失败时释放已打开的连接池
+	if err = db.Use(metrics.NewGormPlugin()); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("gorm use metrics plugin err: %w", err)
+	}
+	if err = db.Use(tracing.NewGormPlugin()); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("gorm use tracing plugin err: %w", err)
+	}
 	sqlDB.SetConnMaxLifetime(time.Second * time.Duration(conf.MaxLifeTime))
 	sqlDB.SetMaxOpenConns(conf.MaxOpenConn)
 	sqlDB.SetMaxIdleConns(conf.MaxIdleConn)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 	if err = sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
 		return nil, fmt.Errorf("ping db err: %w", err)
 	}
 	return db, nil
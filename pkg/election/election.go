@@ -0,0 +1,106 @@
+// Package election 多副本部署时的选主, 保证同一时刻只有一个实例执行单例任务 (如定时清理).
+package election
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrLeadershipLost 执行单例任务期间失去领导权
+var ErrLeadershipLost = errors.New("election: leadership lost")
+
+// Election 选主
+type Election interface {
+	// Campaign 以 candidate 身份参与 name 的选主, 阻塞直到当选或 ctx 取消
+	Campaign(ctx context.Context, name, candidate string) (Leadership, error)
+}
+
+// Leadership 当选后的领导权
+type Leadership interface {
+	// Done 失去领导权 (租约过期、连接断开、Resign) 时关闭
+	Done() <-chan struct{}
+	// Resign 主动放弃领导权
+	Resign(ctx context.Context) error
+}
+
+var _ Election = (*Memory)(nil)
+
+// Memory 进程内选主, 用于测试及单实例部署
+type Memory struct {
+	mu      sync.Mutex
+	leaders map[string]*memLeadership
+}
+
+func NewMemory() *Memory {
+	return &Memory{leaders: make(map[string]*memLeadership)}
+}
+
+// Campaign 参与选主, 当前 leader 失去领导权后由等待者之一当选
+func (m *Memory) Campaign(ctx context.Context, name, candidate string) (Leadership, error) {
+	for {
+		m.mu.Lock()
+		cur, ok := m.leaders[name]
+		if !ok {
+			l := &memLeadership{m: m, name: name, candidate: candidate, done: make(chan struct{})}
+			m.leaders[name] = l
+			m.mu.Unlock()
+			return l, nil
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-cur.done:
+		}
+	}
+}
+
+// Leader 返回 name 当前的 leader, 没有时返回空
+func (m *Memory) Leader(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.leaders[name]; ok {
+		return l.candidate
+	}
+	return ""
+}
+
+// Revoke 强制撤销 name 当前的领导权, 模拟租约过期
+func (m *Memory) Revoke(name string) {
+	m.mu.Lock()
+	l, ok := m.leaders[name]
+	m.mu.Unlock()
+	if ok {
+		l.release()
+	}
+}
+
+type memLeadership struct {
+	m         *Memory
+	name      string
+	candidate string
+	once      sync.Once
+	done      chan struct{}
+}
+
+func (l *memLeadership) Done() <-chan struct{} {
+	return l.done
+}
+
+func (l *memLeadership) Resign(_ context.Context) error {
+	l.release()
+	return nil
+}
+
+func (l *memLeadership) release() {
+	l.once.Do(func() {
+		l.m.mu.Lock()
+		if l.m.leaders[l.name] == l {
+			delete(l.m.leaders, l.name)
+		}
+		l.m.mu.Unlock()
+		close(l.done)
+	})
+}
@@ -0,0 +1,54 @@
+package election_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/election"
+)
+
+func TestMemory(t *testing.T) {
+	m := election.NewMemory()
+	ctx := context.Background()
+	l1, err := m.Campaign(ctx, "cleanup", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Leader("cleanup") != "a" {
+		t.Fatalf("unexpected leader: %s", m.Leader("cleanup"))
+	}
+
+	// 已有 leader 时阻塞到 ctx 取消
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err = m.Campaign(tctx, "cleanup", "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+
+	elected := make(chan election.Leadership)
+	go func() {
+		l, _ := m.Campaign(ctx, "cleanup", "b")
+		elected <- l
+	}()
+	m.Revoke("cleanup")
+	select {
+	case <-l1.Done():
+	default:
+		t.Fatal("revoked leadership not done")
+	}
+	l2 := <-elected
+	if m.Leader("cleanup") != "b" {
+		t.Fatalf("unexpected leader: %s", m.Leader("cleanup"))
+	}
+	// 失去领导权后 Resign 不影响新 leader
+	_ = l1.Resign(ctx)
+	if m.Leader("cleanup") != "b" {
+		t.Fatalf("unexpected leader: %s", m.Leader("cleanup"))
+	}
+	_ = l2.Resign(ctx)
+	if m.Leader("cleanup") != "" {
+		t.Fatalf("unexpected leader: %s", m.Leader("cleanup"))
+	}
+}
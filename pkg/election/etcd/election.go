@@ -0,0 +1,93 @@
+package etcd
+
+import (
+	"context"
+	"math"
+	"path"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/election"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// Option is etcd election option.
+type Option func(o *options)
+
+type options struct {
+	ctx       context.Context
+	namespace string
+	ttl       time.Duration
+}
+
+// Context with election context, leaderships end when it is done.
+func Context(ctx context.Context) Option {
+	return func(o *options) { o.ctx = ctx }
+}
+
+// Namespace with election namespace.
+func Namespace(ns string) Option {
+	return func(o *options) { o.namespace = ns }
+}
+
+// TTL with session ttl, leadership is lost within ttl after the leader goes away.
+// etcd 租约以秒为单位, 不足 1 秒的部分向上取整.
+func TTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+var _ election.Election = (*Election)(nil)
+
+// Election is etcd election.
+// 每次 Campaign 使用独立的 session (租约), 租约续期失败即失去领导权.
+type Election struct {
+	opts   *options
+	client *clientv3.Client
+}
+
+// New creates etcd election
+func New(client *clientv3.Client, opts ...Option) *Election {
+	op := &options{
+		ctx:       context.Background(),
+		namespace: "/election",
+		ttl:       time.Second * 15,
+	}
+	for _, o := range opts {
+		o(op)
+	}
+	return &Election{opts: op, client: client}
+}
+
+// Campaign 参与选主, 阻塞直到当选或 ctx 取消
+func (e *Election) Campaign(ctx context.Context, name, candidate string) (election.Leadership, error) {
+	session, err := concurrency.NewSession(e.client,
+		concurrency.WithTTL(int(math.Ceil(e.opts.ttl.Seconds()))),
+		concurrency.WithContext(e.opts.ctx))
+	if err != nil {
+		return nil, err
+	}
+	el := concurrency.NewElection(session, path.Join(e.opts.namespace, name))
+	if err = el.Campaign(ctx, candidate); err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	return &leadership{session: session, election: el}, nil
+}
+
+type leadership struct {
+	session  *concurrency.Session
+	election *concurrency.Election
+}
+
+func (l *leadership) Done() <-chan struct{} {
+	return l.session.Done()
+}
+
+// Resign 删除 leader key 并撤销租约, 等待者立即当选
+func (l *leadership) Resign(ctx context.Context) error {
+	err := l.election.Resign(ctx)
+	if cerr := l.session.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package etcd_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/election"
+	"github.com/gogoclouds/project-layout/pkg/election/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// newClient 启动内嵌 etcd 并返回客户端
+func newClient(t *testing.T) *clientv3.Client {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	lcurl, _ := url.Parse("http://127.0.0.1:0")
+	lpurl, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{*lcurl}, []url.URL{*lcurl}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{*lpurl}, []url.URL{*lpurl}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd server not ready")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{e.Clients[0].Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// leader 返回 name 当前 leader 的 candidate 及租约
+func leader(t *testing.T, client *clientv3.Client, name string) (string, clientv3.LeaseID) {
+	t.Helper()
+	resp, err := client.Get(context.Background(), "/election/"+name+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return "", 0
+	}
+	return string(resp.Kvs[0].Value), clientv3.LeaseID(resp.Kvs[0].Lease)
+}
+
+func TestElection(t *testing.T) {
+	client := newClient(t)
+	e := etcd.New(client, etcd.TTL(5*time.Second))
+	ctx := context.Background()
+
+	l1, err := e.Campaign(ctx, "cleanup", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := leader(t, client, "cleanup"); name != "a" {
+		t.Fatalf("unexpected leader: %q", name)
+	}
+
+	// 已有 leader 时阻塞到 ctx 取消
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err = e.Campaign(tctx, "cleanup", "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+
+	// Resign 后等待者当选
+	elected := make(chan election.Leadership)
+	go func() {
+		l, _ := e.Campaign(ctx, "cleanup", "c")
+		elected <- l
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err = l1.Resign(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l1.Done():
+	case <-time.After(time.Second):
+		t.Fatal("resigned leadership not done")
+	}
+	var l2 election.Leadership
+	select {
+	case l2 = <-elected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting candidate not elected")
+	}
+	if l2 == nil {
+		t.Fatal("waiting candidate campaign failed")
+	}
+	name, lease := leader(t, client, "cleanup")
+	if name != "c" {
+		t.Fatalf("unexpected leader: %q", name)
+	}
+
+	// 租约撤销后失去领导权
+	if _, err = client.Revoke(ctx, lease); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l2.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lost leadership not done")
+	}
+	if name, _ = leader(t, client, "cleanup"); name != "" {
+		t.Fatalf("expected no leader, got %q", name)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+	l, err := etcd.New(client, etcd.TTL(500*time.Millisecond)).Campaign(ctx, "ttl", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Resign(ctx)
+	_, lease := leader(t, client, "ttl")
+	resp, err := client.TimeToLive(ctx, lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不足 1 秒向上取整 (服务端可能再提升到最小租约时长), 而不是退化为 etcd 默认的 60s
+	if resp.GrantedTTL < 1 || resp.GrantedTTL > 5 {
+		t.Fatalf("granted ttl = %d, want rounded up from 500ms", resp.GrantedTTL)
+	}
+}
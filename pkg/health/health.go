@@ -0,0 +1,202 @@
+// Package health 服务健康检查
+//  1. 存活 (liveness): 进程能响应请求即为存活.
+//  2. 就绪 (readiness): App 启动完成 (hooks、依赖组件、注册中心) 且所有检查项通过才算就绪,
+//     开始关闭时立即置为未就绪.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status 检查结果状态
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+const defaultCheckTimeout = 3 * time.Second
+
+// CheckFunc 检查项, 返回 nil 表示通过
+type CheckFunc func(ctx context.Context) error
+
+// DetailCheckFunc 带详情的检查项, details 原样输出到 Result.Details (如后台任务的运行状态)
+type DetailCheckFunc func(ctx context.Context) (details any, err error)
+
+// Result 单个检查项结果
+type Result struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	Details  any    `json:"details,omitempty"`
+}
+
+// Report 就绪检查报告
+type Report struct {
+	Status Status            `json:"status"`
+	Ready  bool              `json:"ready"` // App 生命周期是否处于就绪状态
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Err 聚合失败检查项的错误, 不包含生命周期就绪状态
+func (r Report) Err() error {
+	names := make([]string, 0, len(r.Checks))
+	for name := range r.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		if res := r.Checks[name]; res.Status != StatusUp {
+			errs = append(errs, fmt.Errorf("%s: %s", name, res.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type Option func(c *Checker)
+
+// WithTimeout 单个检查项的超时时间, 默认 3s
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = timeout
+	}
+}
+
+// Checker 管理就绪状态和检查项, 可同时供 http、rpc 服务使用
+type Checker struct {
+	mu        sync.RWMutex
+	ready     bool
+	timeout   time.Duration
+	checks    map[string]DetailCheckFunc
+	listeners []func(ready bool)
+}
+
+// New 创建 Checker, 初始为未就绪
+func New(opts ...Option) *Checker {
+	c := &Checker{
+		timeout: defaultCheckTimeout,
+		checks:  make(map[string]DetailCheckFunc),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// AddCheck 添加检查项, 同名覆盖
+func (c *Checker) AddCheck(name string, fn CheckFunc) {
+	c.AddDetailCheck(name, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+}
+
+// AddDetailCheck 添加带详情的检查项, 同名覆盖
+func (c *Checker) AddDetailCheck(name string, fn DetailCheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = fn
+}
+
+// OnChange 就绪状态变化时回调, 注册时会以当前状态回调一次
+func (c *Checker) OnChange(fn func(ready bool)) {
+	c.mu.Lock()
+	c.listeners = append(c.listeners, fn)
+	ready := c.ready
+	c.mu.Unlock()
+	fn(ready)
+}
+
+// SetReady 设置就绪状态, 状态变化时通知 OnChange 回调
+func (c *Checker) SetReady(ready bool) {
+	c.mu.Lock()
+	if c.ready == ready {
+		c.mu.Unlock()
+		return
+	}
+	c.ready = ready
+	listeners := append([]func(bool){}, c.listeners...)
+	c.mu.Unlock()
+	for _, fn := range listeners {
+		fn(ready)
+	}
+}
+
+// Ready 当前是否处于就绪状态 (不执行检查项)
+func (c *Checker) Ready() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ready
+}
+
+// Check 并发执行所有检查项, 生成就绪报告
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	ready := c.ready
+	checks := make(map[string]DetailCheckFunc, len(c.checks))
+	for name, fn := range c.checks {
+		checks[name] = fn
+	}
+	c.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Ready: ready, Checks: make(map[string]Result, len(checks))}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for name, fn := range checks {
+		name, fn := name, fn
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := c.run(ctx, fn)
+			mu.Lock()
+			report.Checks[name] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if !ready {
+		report.Status = StatusDown
+	}
+	for _, res := range report.Checks {
+		if res.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, fn DetailCheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	type result struct {
+		details any
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		details, err := fn(ctx)
+		done <- result{details: details, err: err}
+	}()
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		r.err = ctx.Err()
+	}
+	err := r.err
+	res := Result{Status: StatusUp, Duration: time.Since(start).String(), Details: r.details}
+	if err != nil {
+		res.Status, res.Error = StatusDown, err.Error()
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/health"
+)
+
+func TestChecker(t *testing.T) {
+	c := health.New(health.WithTimeout(50 * time.Millisecond))
+	var changes []bool
+	c.OnChange(func(ready bool) {
+		changes = append(changes, ready)
+	})
+
+	c.AddCheck("ok", func(context.Context) error { return nil })
+	if report := c.Check(context.Background()); report.Status != health.StatusDown || report.Ready {
+		t.Fatalf("expected down before ready: %+v", report)
+	}
+
+	c.SetReady(true)
+	c.SetReady(true)
+	if report := c.Check(context.Background()); report.Status != health.StatusUp || report.Err() != nil {
+		t.Fatalf("expected up: %+v", report)
+	}
+
+	c.AddCheck("fail", func(context.Context) error { return errors.New("boom") })
+	c.AddCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	report := c.Check(context.Background())
+	if report.Status != health.StatusDown {
+		t.Fatalf("expected down: %+v", report)
+	}
+	if report.Checks["ok"].Status != health.StatusUp ||
+		report.Checks["fail"].Error != "boom" ||
+		report.Checks["slow"].Status != health.StatusDown {
+		t.Fatalf("unexpected checks: %+v", report.Checks)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "fail: boom") {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	c.SetReady(false)
+	if len(changes) != 3 || changes[0] || !changes[1] || changes[2] {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+}
+
+func TestDetailCheck(t *testing.T) {
+	c := health.New()
+	c.SetReady(true)
+	c.AddDetailCheck("worker", func(context.Context) (any, error) {
+		return map[string]int{"restarts": 3}, errors.New("failed")
+	})
+	res := c.Check(context.Background()).Checks["worker"]
+	if res.Status != health.StatusDown || res.Error != "failed" || res.Details.(map[string]int)["restarts"] != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 )
 
@@ -49,68 +50,45 @@ func Extract(hostport string, lis net.Listener) (string, error) {
 		return net.JoinHostPort(addr, port), nil
 	}
 
-	ifaces, err := net.Interfaces()
+	ip, err := localIP()
 	if err != nil {
 		return "", err
 	}
-	lowest := int(^uint(0) >> 1)
-	var result net.IP
-	for _, iface := range ifaces {
-		if (iface.Flags & net.FlagUp) == 0 {
-			continue
-		}
-		if iface.Index < lowest || result == nil {
-			lowest = iface.Index
-		}
-		if result != nil {
-			continue
-		}
-		addrs, err := iface.Addrs()
-		if err != nil {
-			continue
-		}
-		for _, rawAddr := range addrs {
-			var ip net.IP
-			switch addr := rawAddr.(type) {
-			case *net.IPAddr:
-				ip = addr.IP
-			case *net.IPNet:
-				ip = addr.IP
-			default:
-				continue
-			}
-			if isValidIP(ip.String()) {
-				result = ip
-			}
-		}
-	}
-	if result != nil {
-		return net.JoinHostPort(result.String(), port), nil
+	if ip != nil {
+		return net.JoinHostPort(ip.String(), port), nil
 	}
 	return "", nil
 }
 
 func OutBoundIP() (string, error) {
-	ifaces, err := net.Interfaces()
+	ip, err := localIP()
 	if err != nil {
 		return "", err
 	}
-	lowest := int(^uint(0) >> 1)
-	var result net.IP
+	if ip == nil {
+		return "", errors.New("no valid ip found")
+	}
+	return ip.String(), nil
+}
+
+// localIP 本机对外 ip: 取编号最小的已启用、非回环网卡上的单播地址, 同一网卡优先 IPv4
+func localIP() (net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ifaces, func(i, j int) bool {
+		return ifaces[i].Index < ifaces[j].Index
+	})
 	for _, iface := range ifaces {
-		if (iface.Flags & net.FlagUp) == 0 {
-			continue
-		}
-		if iface.Index < lowest || result == nil {
-			lowest = iface.Index
-		}
-		if result != nil {
+		if (iface.Flags&net.FlagUp) == 0 || (iface.Flags&net.FlagLoopback) != 0 {
 			continue
 		}
 		addrs, err := iface.Addrs()
 		if err != nil {
 			continue
 		}
+		var result net.IP
 		for _, rawAddr := range addrs {
 			var ip net.IP
 			switch addr := rawAddr.(type) {
@@ -121,13 +99,19 @@ func OutBoundIP() (string, error) {
 			default:
 				continue
 			}
-			if isValidIP(ip.String()) {
+			if !isValidIP(ip.String()) {
+				continue
+			}
+			if ip.To4() != nil {
+				return ip, nil
+			}
+			if result == nil {
 				result = ip
 			}
 		}
+		if result != nil {
+			return result, nil
+		}
 	}
-	if result == nil {
-		return "", errors.New("no valid ip found")
-	}
-	return result.String(), nil
+	return nil, nil
 }
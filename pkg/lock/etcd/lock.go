@@ -0,0 +1,121 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/lock"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// Option is etcd locker option.
+type Option func(o *options)
+
+type options struct {
+	namespace string
+	ttl       time.Duration
+}
+
+// Namespace with lock namespace.
+func Namespace(ns string) Option {
+	return func(o *options) { o.namespace = ns }
+}
+
+// TTL with session ttl, the lock is released within ttl after the holder goes away.
+// etcd 租约以秒为单位, 不足 1 秒的部分向上取整.
+func TTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+var _ lock.Locker = (*Locker)(nil)
+
+// Locker is etcd locker.
+// 每把锁使用独立的 session (租约), 由租约 KeepAlive 自动续期, 续期失败即丢失锁.
+// fencing token 为加锁时的 etcd revision.
+type Locker struct {
+	opts   *options
+	client *clientv3.Client
+}
+
+// New creates etcd locker
+func New(client *clientv3.Client, opts ...Option) *Locker {
+	op := &options{
+		namespace: "/lock",
+		ttl:       time.Second * 10,
+	}
+	for _, o := range opts {
+		o(op)
+	}
+	return &Locker{opts: op, client: client}
+}
+
+func (l *Locker) TryLock(ctx context.Context, key string) (lock.Lock, error) {
+	return l.obtain(ctx, key, (*concurrency.Mutex).TryLock)
+}
+
+func (l *Locker) Lock(ctx context.Context, key string) (lock.Lock, error) {
+	return l.obtain(ctx, key, (*concurrency.Mutex).Lock)
+}
+
+func (l *Locker) obtain(ctx context.Context, key string, fn func(*concurrency.Mutex, context.Context) error) (lock.Lock, error) {
+	session, err := concurrency.NewSession(l.client, concurrency.WithTTL(int(math.Ceil(l.opts.ttl.Seconds()))))
+	if err != nil {
+		return nil, err
+	}
+	m := concurrency.NewMutex(session, path.Join(l.opts.namespace, key))
+	if err = fn(m, ctx); err != nil {
+		_ = session.Close()
+		if errors.Is(err, concurrency.ErrLocked) {
+			return nil, lock.ErrNotObtained
+		}
+		return nil, err
+	}
+	// 持有者 key 的创建版本号作为 fencing token, Token 不带 ctx, 加锁时读取一次
+	resp, err := l.client.Get(ctx, m.Key())
+	if err == nil && len(resp.Kvs) == 0 {
+		err = lock.ErrNotHeld
+	}
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	return &mutex{key: key, session: session, mutex: m, token: resp.Kvs[0].CreateRevision}, nil
+}
+
+type mutex struct {
+	key     string
+	session *concurrency.Session
+	mutex   *concurrency.Mutex
+	token   int64
+}
+
+func (m *mutex) Key() string {
+	return m.key
+}
+
+// Token 持有者 key (namespace/key/lease) 的 CreateRevision. 同一 key 的等待者按 CreateRevision 排队获得锁,
+// 因此后获得锁的持有者 token 更大; 不使用 Header().Revision, 它是响应时的集群版本号, 与本次加锁无关
+func (m *mutex) Token() int64 {
+	return m.token
+}
+
+func (m *mutex) Done() <-chan struct{} {
+	return m.session.Done()
+}
+
+func (m *mutex) Unlock(ctx context.Context) error {
+	select {
+	case <-m.session.Done():
+		return lock.ErrNotHeld
+	default:
+	}
+	err := m.mutex.Unlock(ctx)
+	if cerr := m.session.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
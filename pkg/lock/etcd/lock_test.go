@@ -0,0 +1,113 @@
+package etcd_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/lock"
+	"github.com/gogoclouds/project-layout/pkg/lock/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// newClient 启动内嵌 etcd 并返回客户端
+func newClient(t *testing.T) *clientv3.Client {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	lcurl, _ := url.Parse("http://127.0.0.1:0")
+	lpurl, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{*lcurl}, []url.URL{*lcurl}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{*lpurl}, []url.URL{*lpurl}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd server not ready")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{e.Clients[0].Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestLock(t *testing.T) {
+	client := newClient(t)
+	locker := etcd.New(client, etcd.TTL(5*time.Second))
+	ctx := context.Background()
+
+	l1, err := locker.TryLock(ctx, "order/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = locker.TryLock(ctx, "order/1"); !errors.Is(err, lock.ErrNotObtained) {
+		t.Fatalf("expected not obtained, got: %v", err)
+	}
+
+	obtained := make(chan lock.Lock)
+	go func() {
+		l, _ := locker.Lock(ctx, "order/1")
+		obtained <- l
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err = l1.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	l2 := <-obtained
+	if l2 == nil || l2.Token() <= l1.Token() {
+		t.Fatalf("expected increasing fencing token")
+	}
+	// token 为持有者 key 的创建版本号, 不随其他写入变化
+	if _, err = client.Put(ctx, "other", "v"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(ctx, "/lock/order/1/", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || l2.Token() != resp.Kvs[0].CreateRevision {
+		t.Fatalf("token = %d, want holder key create revision", l2.Token())
+	}
+	if err = l1.Unlock(ctx); !errors.Is(err, lock.ErrNotHeld) {
+		t.Fatalf("expected not held, got: %v", err)
+	}
+
+	// 租约撤销后丢失锁
+	l3, err := etcd.New(client).TryLock(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leases, err := client.Leases(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lease := range leases.Leases {
+		resp, err := client.TimeToLive(ctx, lease.ID, clientv3.WithAttachedKeys())
+		if err == nil && len(resp.Keys) > 0 && strings.HasPrefix(string(resp.Keys[0]), "/lock/job/") {
+			_, _ = client.Revoke(ctx, lease.ID)
+		}
+	}
+	select {
+	case <-l3.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lost lock not done")
+	}
+	if err = l2.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+// Package lock 跨副本的分布式锁.
+//
+// 每次加锁成功返回单调递增的 fencing token, 锁可能因网络分区、GC 停顿等原因在持有者不知情时过期,
+// 写入共享资源时携带 token 并由资源方拒绝较小的 token, 才能保证互斥.
+package lock
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotObtained TryLock 时锁已被其他持有者占用
+	ErrNotObtained = errors.New("lock: not obtained")
+	// ErrNotHeld Unlock 时锁已过期或被其他持有者占用
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+// Locker 分布式锁
+type Locker interface {
+	// TryLock 尝试加锁, 锁已被占用时立即返回 ErrNotObtained
+	TryLock(ctx context.Context, key string) (Lock, error)
+	// Lock 加锁, 阻塞直到成功或 ctx 取消
+	Lock(ctx context.Context, key string) (Lock, error)
+}
+
+// Lock 已持有的锁, 持有期间自动续期
+type Lock interface {
+	Key() string
+	// Token fencing token, 同一 key 每次加锁单调递增
+	Token() int64
+	// Done 锁丢失 (续期失败、过期) 或 Unlock 后关闭
+	Done() <-chan struct{}
+	// Unlock 释放锁, 锁已丢失时返回 ErrNotHeld
+	Unlock(ctx context.Context) error
+}
@@ -0,0 +1,198 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/lock"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/util"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	// 加锁成功时递增并返回 fencing token, 失败返回 0
+	obtainScript = goredis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0`)
+	// 仅持有者可以续期
+	renewScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	// 仅持有者可以释放
+	releaseScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Option is redis locker option.
+type Option func(o *options)
+
+type options struct {
+	prefix        string
+	ttl           time.Duration
+	retryInterval time.Duration
+}
+
+// Prefix with lock key prefix.
+func Prefix(prefix string) Option {
+	return func(o *options) { o.prefix = prefix }
+}
+
+// TTL with lock ttl, the watchdog renews the lock every ttl/3.
+func TTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+// RetryInterval with the interval Lock retries to obtain the lock.
+func RetryInterval(d time.Duration) Option {
+	return func(o *options) { o.retryInterval = d }
+}
+
+var _ lock.Locker = (*Locker)(nil)
+
+// Locker is redis locker.
+// SET NX PX 加锁, Lua 脚本校验持有者后续期、释放, 持有期间由 watchdog 自动续期.
+type Locker struct {
+	opts   *options
+	client goredis.UniversalClient
+}
+
+// New creates redis locker
+func New(client goredis.UniversalClient, opts ...Option) *Locker {
+	op := &options{
+		prefix:        "lock:",
+		ttl:           time.Second * 10,
+		retryInterval: time.Millisecond * 100,
+	}
+	for _, o := range opts {
+		o(op)
+	}
+	return &Locker{opts: op, client: client}
+}
+
+func (l *Locker) TryLock(ctx context.Context, key string) (lock.Lock, error) {
+	value := util.UUID()
+	k := l.key(key)
+	token, err := obtainScript.Run(ctx, l.client, []string{k, k + ":fence"}, value, l.opts.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if token == 0 {
+		return nil, lock.ErrNotObtained
+	}
+	m := &mutex{
+		locker: l,
+		key:    key,
+		value:  value,
+		token:  token,
+		done:   make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	go m.watchdog()
+	return m, nil
+}
+
+// key 锁在 redis 中的 key, 使用 hash tag 使锁与 fencing 计数器 (key:fence) 位于同一 cluster slot
+func (l *Locker) key(key string) string {
+	return "{" + l.opts.prefix + key + "}"
+}
+
+func (l *Locker) Lock(ctx context.Context, key string) (lock.Lock, error) {
+	ticker := time.NewTicker(l.opts.retryInterval)
+	defer ticker.Stop()
+	for {
+		m, err := l.TryLock(ctx, key)
+		if !errors.Is(err, lock.ErrNotObtained) {
+			return m, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+type mutex struct {
+	locker *Locker
+	key    string
+	value  string // 持有者标识
+	token  int64
+
+	once sync.Once
+	done chan struct{} // 锁丢失或释放
+	stop chan struct{} // 停止 watchdog
+}
+
+func (m *mutex) Key() string {
+	return m.key
+}
+
+func (m *mutex) Token() int64 {
+	return m.token
+}
+
+func (m *mutex) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *mutex) Unlock(ctx context.Context) error {
+	m.release()
+	res, err := releaseScript.Run(ctx, m.locker.client, []string{m.locker.key(m.key)}, m.value).Int64()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return lock.ErrNotHeld
+	}
+	return nil
+}
+
+// watchdog 每 ttl/3 续期一次, 续期失败直到锁过期则视为丢失
+func (m *mutex) watchdog() {
+	ttl := m.locker.opts.ttl
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	deadline := time.Now().Add(ttl)
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		res, err := renewScript.Run(ctx, m.locker.client, []string{m.locker.key(m.key)}, m.value, ttl.Milliseconds()).Int64()
+		cancel()
+		switch {
+		case err == nil && res == 1:
+			deadline = time.Now().Add(ttl)
+		case err == nil:
+			// 锁已过期或被其他持有者占用
+			logger.Errorf("lock %s lost", m.key)
+			m.release()
+			return
+		case time.Now().After(deadline):
+			logger.Errorf("lock %s lost: renew error: %v", m.key, err)
+			m.release()
+			return
+		default:
+			logger.Errorf("lock %s renew error: %v", m.key, err)
+		}
+	}
+}
+
+func (m *mutex) release() {
+	m.once.Do(func() {
+		close(m.stop)
+		close(m.done)
+	})
+}
@@ -0,0 +1,140 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gogoclouds/project-layout/pkg/lock"
+	"github.com/gogoclouds/project-layout/pkg/lock/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newLocker(t *testing.T, opts ...redis.Option) (*redis.Locker, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return redis.New(client, opts...), mr
+}
+
+func TestLock(t *testing.T) {
+	locker, _ := newLocker(t, redis.RetryInterval(5*time.Millisecond))
+	ctx := context.Background()
+
+	l1, err := locker.TryLock(ctx, "order:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l1.Token() != 1 {
+		t.Fatalf("unexpected token: %d", l1.Token())
+	}
+	if _, err = locker.TryLock(ctx, "order:1"); !errors.Is(err, lock.ErrNotObtained) {
+		t.Fatalf("expected not obtained, got: %v", err)
+	}
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err = locker.Lock(tctx, "order:1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+
+	obtained := make(chan lock.Lock)
+	go func() {
+		l, _ := locker.Lock(ctx, "order:1")
+		obtained <- l
+	}()
+	if err = l1.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	l2 := <-obtained
+	if l2 == nil || l2.Token() <= l1.Token() {
+		t.Fatalf("expected increasing fencing token")
+	}
+	if err = l1.Unlock(ctx); !errors.Is(err, lock.ErrNotHeld) {
+		t.Fatalf("expected not held, got: %v", err)
+	}
+	if err = l2.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	locker, mr := newLocker(t, redis.TTL(60*time.Millisecond))
+	ctx := context.Background()
+	l, err := locker.TryLock(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// watchdog 续期, 锁不会过期
+	time.Sleep(150 * time.Millisecond)
+	if !mr.Exists("{lock:job}") {
+		t.Fatal("lock expired while held")
+	}
+
+	// 锁过期后被其他持有者占用
+	mr.FastForward(time.Second)
+	l2, err := locker.TryLock(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lost lock not done")
+	}
+	if err = l.Unlock(ctx); !errors.Is(err, lock.ErrNotHeld) {
+		t.Fatalf("expected not held, got: %v", err)
+	}
+	if err = l2.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockCluster(t *testing.T) {
+	mr := miniredis.RunT(t)
+	// miniredis 响应 CLUSTER SLOTS, 以单节点集群运行
+	client := goredis.NewClusterClient(&goredis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	t.Cleanup(func() { _ = client.Close() })
+	locker := redis.New(client)
+	ctx := context.Background()
+
+	l, err := locker.TryLock(ctx, "order:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 脚本访问的 key 必须位于同一 slot, 否则 redis cluster 返回 CROSSSLOT
+	keys := mr.Keys()
+	if len(keys) != 2 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	if keySlot(keys[0]) != keySlot(keys[1]) {
+		t.Fatalf("keys %v hash to different slots", keys)
+	}
+	if err = l.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// keySlot redis cluster 的 slot: 有 hash tag 时只计算 {} 中的部分
+func keySlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16 // CRC16-XMODEM
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc % 16384
+}
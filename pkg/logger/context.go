@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+type (
+	fieldsKey    struct{}
+	requestIDKey struct{}
+	userIDKey    struct{}
+)
+
+// WithFields 将日志字段附加到 ctx, 使用 *c 方法 (如 Infoc) 时输出, 同名字段以后附加的为准
+func WithFields(ctx context.Context, keysAndValues ...any) context.Context {
+	if len(keysAndValues) == 0 {
+		return ctx
+	}
+	parent, _ := ctx.Value(fieldsKey{}).([]any)
+	fields := make([]any, 0, len(parent)+len(keysAndValues))
+	fields = append(fields, parent...)
+	index := make(map[string]int, len(parent)/2) // key -> 值在 fields 中的下标
+	for i := 0; i+1 < len(parent); i += 2 {
+		if k, ok := parent[i].(string); ok {
+			index[k] = i + 1
+		}
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, keysAndValues[i])
+			break
+		}
+		k, ok := keysAndValues[i].(string)
+		if j, dup := index[k]; ok && dup {
+			fields[j] = keysAndValues[i+1] // 保留原位置, 值以后附加的为准
+			continue
+		}
+		fields = append(fields, keysAndValues[i], keysAndValues[i+1])
+		if ok {
+			index[k] = len(fields) - 1
+		}
+	}
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// WithRequestID 将请求 ID 写入 ctx, 日志字段为 request_id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 从 ctx 中获取请求 ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithUserID 将当前用户 ID 写入 ctx, 日志字段为 user_id
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// UserID 从 ctx 中获取当前用户 ID
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey{}).(string)
+	return id
+}
+
+// Fields 返回 ctx 中的日志字段: request_id、trace_id、span_id、user_id、grpc_method 及 WithFields 附加的字段
+func Fields(ctx context.Context) []any {
+	if ctx == nil {
+		return nil
+	}
+	var fields []any
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, "request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+	if id := UserID(ctx); id != "" {
+		fields = append(fields, "user_id", id)
+	}
+	if method, ok := grpc.Method(ctx); ok {
+		fields = append(fields, "grpc_method", method)
+	}
+	if extra, ok := ctx.Value(fieldsKey{}).([]any); ok {
+		fields = append(fields, extra...)
+	}
+	return fields
+}
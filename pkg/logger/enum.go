@@ -7,12 +7,14 @@ type LogLevel string
 const (
 	LogLevel_Debug LogLevel = "debug"
 	LogLevel_Error LogLevel = "error"
+	LogLevel_Warn  LogLevel = "warn"
 	LogLevel_Info  LogLevel = "info"
 )
 
 var logLevelMap = map[string]LogLevel{
 	"debug": LogLevel_Debug,
 	"error": LogLevel_Error,
+	"warn":  LogLevel_Warn,
 	"info":  LogLevel_Info,
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 )
 
+// ILogger 日志接口, *c 方法额外输出 ctx 中的日志字段 (见 Fields、WithFields)
 type ILogger interface {
 	Panic(msg string, keysAndValues ...any)
 	Panicf(format string, args ...any)
@@ -15,6 +16,11 @@ type ILogger interface {
 	Errorc(ctx context.Context, msg string, keysAndValues ...any)
 	Errorcf(ctx context.Context, format string, args ...any)
 
+	Warn(msg string, keysAndValues ...any)
+	Warnf(format string, args ...any)
+	Warnc(ctx context.Context, msg string, keysAndValues ...any)
+	Warncf(ctx context.Context, format string, args ...any)
+
 	Info(msg string, keysAndValues ...any)
 	Infof(format string, args ...any)
 	Infoc(ctx context.Context, msg string, keysAndValues ...any)
@@ -40,6 +46,14 @@ func Panicf(template string, args ...any) {
 	log.Panicf(template, args...)
 }
 
+func Panicc(ctx context.Context, msg string, keysAndValues ...any) {
+	log.Panicc(ctx, msg, keysAndValues...)
+}
+
+func Paniccf(ctx context.Context, template string, args ...any) {
+	log.Paniccf(ctx, template, args...)
+}
+
 func Error(msg string, keysAndValues ...any) {
 	log.Error(msg, keysAndValues...)
 }
@@ -48,6 +62,30 @@ func Errorf(template string, args ...any) {
 	log.Errorf(template, args...)
 }
 
+func Errorc(ctx context.Context, msg string, keysAndValues ...any) {
+	log.Errorc(ctx, msg, keysAndValues...)
+}
+
+func Errorcf(ctx context.Context, template string, args ...any) {
+	log.Errorcf(ctx, template, args...)
+}
+
+func Warn(msg string, keysAndValues ...any) {
+	log.Warn(msg, keysAndValues...)
+}
+
+func Warnf(template string, args ...any) {
+	log.Warnf(template, args...)
+}
+
+func Warnc(ctx context.Context, msg string, keysAndValues ...any) {
+	log.Warnc(ctx, msg, keysAndValues...)
+}
+
+func Warncf(ctx context.Context, template string, args ...any) {
+	log.Warncf(ctx, template, args...)
+}
+
 func Info(msg string, keysAndValues ...any) {
 	log.Info(msg, keysAndValues...)
 }
@@ -56,6 +94,14 @@ func Infof(template string, args ...any) {
 	log.Infof(template, args...)
 }
 
+func Infoc(ctx context.Context, msg string, keysAndValues ...any) {
+	log.Infoc(ctx, msg, keysAndValues...)
+}
+
+func Infocf(ctx context.Context, template string, args ...any) {
+	log.Infocf(ctx, template, args...)
+}
+
 func Debug(msg string, keysAndValues ...any) {
 	log.Debug(msg, keysAndValues...)
 }
@@ -63,3 +109,11 @@ func Debug(msg string, keysAndValues ...any) {
 func Debugf(template string, args ...any) {
 	log.Debugf(template, args...)
 }
+
+func Debugc(ctx context.Context, msg string, keysAndValues ...any) {
+	log.Debugc(ctx, msg, keysAndValues...)
+}
+
+func Debugcf(ctx context.Context, template string, args ...any) {
+	log.Debugcf(ctx, template, args...)
+}
@@ -16,9 +16,9 @@ const (
 )
 
 type Config struct {
-	Level      string `yaml:"level"`
-	LevelCh    chan LogLevel
-	TimeFormat string `yaml:"timeFormat"`
+	Level      string        `yaml:"level"`
+	LevelCh    chan LogLevel `json:"-"`
+	TimeFormat string        `yaml:"timeFormat"`
 
 	// 完整的文件路径名
 	Filepath        string `yaml:"filepath"`
@@ -1,7 +1,10 @@
 package logger_test
 
 import (
+	"context"
+	"fmt"
 	"github.com/gogoclouds/project-layout/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
 	"runtime"
 	"testing"
 )
@@ -40,3 +43,27 @@ func TestLogger(t *testing.T) {
 	// 2023-11-06 23:55:03.638	INFO	logger/logger_test.go:34	The is Infof
 	// 2023-11-06 23:55:03.638	ERROR	logger/logger_test.go:35	The is Errorf
 }
+
+func TestContextFields(t *testing.T) {
+	ctx := context.Background()
+	if fields := logger.Fields(ctx); len(fields) != 0 {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	ctx = logger.WithRequestID(ctx, "req-1")
+	ctx = logger.WithUserID(ctx, "u-1")
+	ctx = logger.WithFields(ctx, "order_id", 40)
+	ctx = logger.WithFields(ctx, "sku", "A-0", "order_id", 41)
+	ctx = logger.WithFields(ctx, "sku", "A-1", "order_id", 42) // 同名字段以后附加的为准
+	tid, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	sid, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid}))
+
+	got := fmt.Sprint(logger.Fields(ctx))
+	want := "[request_id req-1 trace_id 4bf92f3577b34da6a3ce929d0e0e4736 span_id 00f067aa0ba902b7 user_id u-1 order_id 42 sku A-1]"
+	if got != want {
+		t.Fatalf("fields = %s, want %s", got, want)
+	}
+	logger.Infoc(ctx, "The is", "Infoc", "info")
+	logger.Errorcf(ctx, "The is %s", "Errorcf")
+}
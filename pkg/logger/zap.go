@@ -5,6 +5,7 @@ import (
 	"github.com/natefinch/lumberjack"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"net/http"
 	"os"
 )
 
@@ -21,12 +22,11 @@ func (zl *ZapLogger) Panicf(format string, args ...any) {
 }
 
 func (zl *ZapLogger) Panicc(ctx context.Context, msg string, keysAndValues ...any) {
-	zl.logger.Sugar().Panicw(msg, keysAndValues...)
-
+	zl.sugar(ctx).Panicw(msg, keysAndValues...)
 }
 
 func (zl *ZapLogger) Paniccf(ctx context.Context, format string, args ...any) {
-	zl.logger.Sugar().Panicf(format, args...)
+	zl.sugar(ctx).Panicf(format, args...)
 }
 
 func (zl *ZapLogger) Error(msg string, keysAndValues ...any) {
@@ -38,11 +38,27 @@ func (zl *ZapLogger) Errorf(format string, args ...any) {
 }
 
 func (zl *ZapLogger) Errorc(ctx context.Context, msg string, keysAndValues ...any) {
-	zl.logger.Sugar().Errorw(msg, keysAndValues...)
+	zl.sugar(ctx).Errorw(msg, keysAndValues...)
 }
 
 func (zl *ZapLogger) Errorcf(ctx context.Context, format string, args ...any) {
-	zl.logger.Sugar().Errorf(format, args...)
+	zl.sugar(ctx).Errorf(format, args...)
+}
+
+func (zl *ZapLogger) Warn(msg string, keysAndValues ...any) {
+	zl.logger.Sugar().Warnw(msg, keysAndValues...)
+}
+
+func (zl *ZapLogger) Warnf(format string, args ...any) {
+	zl.logger.Sugar().Warnf(format, args...)
+}
+
+func (zl *ZapLogger) Warnc(ctx context.Context, msg string, keysAndValues ...any) {
+	zl.sugar(ctx).Warnw(msg, keysAndValues...)
+}
+
+func (zl *ZapLogger) Warncf(ctx context.Context, format string, args ...any) {
+	zl.sugar(ctx).Warnf(format, args...)
 }
 
 func (zl *ZapLogger) Info(msg string, keysAndValues ...any) {
@@ -54,11 +70,11 @@ func (zl *ZapLogger) Infof(format string, args ...any) {
 }
 
 func (zl *ZapLogger) Infoc(ctx context.Context, msg string, keysAndValues ...any) {
-	zl.logger.Sugar().Infow(msg, keysAndValues...)
+	zl.sugar(ctx).Infow(msg, keysAndValues...)
 }
 
 func (zl *ZapLogger) Infocf(ctx context.Context, format string, args ...any) {
-	zl.Infof(format, args...)
+	zl.sugar(ctx).Infof(format, args...)
 }
 
 func (zl *ZapLogger) Debug(msg string, keysAndValues ...any) {
@@ -70,23 +86,47 @@ func (zl *ZapLogger) Debugf(format string, args ...any) {
 }
 
 func (zl *ZapLogger) Debugc(ctx context.Context, msg string, keysAndValues ...any) {
-	zl.logger.Sugar().Debugw(msg, keysAndValues...)
+	zl.sugar(ctx).Debugw(msg, keysAndValues...)
 }
 
 func (zl *ZapLogger) Debugcf(ctx context.Context, format string, args ...any) {
-	zl.logger.Sugar().Debugf(format, args...)
+	zl.sugar(ctx).Debugf(format, args...)
+}
+
+// sugar 附加 ctx 中的日志字段, 见 Fields
+func (zl *ZapLogger) sugar(ctx context.Context) *zap.SugaredLogger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return zl.logger.Sugar()
+	}
+	return zl.logger.Sugar().With(fields...)
 }
 
 // atomicLevel 动态更新限制日志打印级别
 var atomicLevel zap.AtomicLevel
 
+// 未调用 InitZapLogger 前默认输出到控制台, 避免日志方法空指针
+func init() {
+	atomicLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+	consoleCore := zapcore.NewCore(
+		setConsoleEncoder(timeFormatDefault),
+		zapcore.Lock(os.Stdout),
+		atomicLevel,
+	)
+	SetLogger(&ZapLogger{logger: zap.New(consoleCore, zap.AddCaller(), zap.AddCallerSkip(2))})
+}
+
 func InitZapLogger(conf Config) {
-	atomicLevel = zap.NewAtomicLevel()
-	go func() {
-		for level := range conf.LevelCh {
-			_ = atomicLevel.UnmarshalText([]byte(level))
-		}
-	}()
+	if conf.Level != "" {
+		_ = SetLevel(LogLevel(conf.Level))
+	}
+	if conf.LevelCh != nil {
+		go func() {
+			for level := range conf.LevelCh {
+				_ = SetLevel(level)
+			}
+		}()
+	}
 	fileCore := zapcore.NewCore( // 输出到日志文件
 		setJSONEncoder(conf.TimeFormat, conf.FileJsonEncoder),
 		setLoggerWriter(conf),
@@ -101,6 +141,21 @@ func InitZapLogger(conf Config) {
 	SetLogger(&ZapLogger{logger: l})
 }
 
+// SetLevel 运行时修改日志级别, 对所有输出生效
+func SetLevel(level LogLevel) error {
+	return atomicLevel.UnmarshalText([]byte(level))
+}
+
+// Level 当前日志级别
+func Level() LogLevel {
+	return LogLevel(atomicLevel.Level().String())
+}
+
+// LevelHandler 日志级别 http 接口: GET 返回当前级别, PUT {"level":"debug"} 修改级别
+func LevelHandler() http.Handler {
+	return atomicLevel
+}
+
 func setConsoleEncoder(timeFormat string) zapcore.Encoder {
 	ec := setEncoderConf(timeFormat)
 	ec.EncodeLevel = zapcore.CapitalColorLevelEncoder // 终端输出 日志级别有颜色
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor rpc 请求数及耗时
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeGrpc("unary", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor stream rpc 请求数及耗时 (整个 stream 的持续时间)
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeGrpc("stream", info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeGrpc(typ, method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	grpcHandled.WithLabelValues(typ, method, code).Inc()
+	grpcDuration.WithLabelValues(typ, method, code).Observe(time.Since(start).Seconds())
+}
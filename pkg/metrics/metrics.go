@@ -0,0 +1,96 @@
+// Package metrics Prometheus 指标, 注册到默认 Registry.
+// http、rpc 传输服务、db、redis、注册中心在创建时自动埋点, /metrics 由管理服务暴露, 业务端口需通过 server.WithMetricsRoute (app.WithMetricsRoute) 显式开启.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "Total number of http requests by method, route and status code.",
+	}, []string{"method", "route", "code"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "Http request latency by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	grpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of rpcs completed by type, method and status code.",
+	}, []string{"type", "method", "code"})
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Rpc latency by type, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"type", "method", "code"})
+
+	dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database query latency by operation and table.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "table"})
+	dbErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Total number of failed database queries by operation and table.",
+	}, []string{"operation", "table"})
+
+	redisDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "redis_command_duration_seconds",
+		Help:    "Redis command latency by command.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"})
+	redisErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_command_errors_total",
+		Help: "Total number of failed redis commands by command.",
+	}, []string{"command"})
+
+	registryRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "registry_register_retries_total",
+		Help: "Total number of service re-registration attempts after the registry lease was lost.",
+	}, []string{"service"})
+	registryFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "registry_register_failures_total",
+		Help: "Total number of services that gave up re-registration after max retries.",
+	}, []string{"service"})
+
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Circuit breaker state by name: 0 closed, 1 open, 2 half-open.",
+	}, []string{"name"})
+	breakerRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_rejected_total",
+		Help: "Total number of calls rejected by the circuit breaker by name.",
+	}, []string{"name"})
+)
+
+// Handler Prometheus 指标接口
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegistryRetry 注册中心租约丢失后重新注册一次
+func RegistryRetry(service string) {
+	registryRetries.WithLabelValues(service).Inc()
+}
+
+// RegistryFailure 注册中心重新注册的重试次数耗尽
+func RegistryFailure(service string) {
+	registryFailures.WithLabelValues(service).Inc()
+}
+
+// BreakerState 熔断器状态变化: 0 关闭, 1 打开, 2 半开
+func BreakerState(name string, state int) {
+	breakerState.WithLabelValues(name).Set(float64(state))
+}
+
+// BreakerRejected 熔断器拒绝一次调用
+func BreakerRejected(name string) {
+	breakerRejected.WithLabelValues(name).Inc()
+}
@@ -0,0 +1,111 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// expectMetrics 抓取 /metrics 并检查包含所有样本
+func expectMetrics(t *testing.T, samples ...string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, s := range samples {
+		if !strings.Contains(body, s) {
+			t.Errorf("missing sample: %s", s)
+		}
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(metrics.GinMiddleware())
+	e.GET("/user/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	for _, path := range []string{"/user/1", "/user/2", "/missing"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	expectMetrics(t,
+		`http_server_requests_total{code="204",method="GET",route="/user/:id"} 2`,
+		`http_server_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`http_server_request_duration_seconds_count{code="204",method="GET",route="/user/:id"} 2`,
+	)
+}
+
+func TestGrpcInterceptor(t *testing.T) {
+	unary := metrics.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/greeter.Greeter/SayHello"}
+	_, _ = unary(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	_, _ = unary(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	stream := metrics.StreamServerInterceptor()
+	_ = stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/greeter.Greeter/Chat"},
+		func(srv any, ss grpc.ServerStream) error { return errors.New("boom") })
+	expectMetrics(t,
+		`grpc_server_handled_total{code="OK",method="/greeter.Greeter/SayHello",type="unary"} 1`,
+		`grpc_server_handled_total{code="NotFound",method="/greeter.Greeter/SayHello",type="unary"} 1`,
+		`grpc_server_handled_total{code="Unknown",method="/greeter.Greeter/Chat",type="stream"} 1`,
+	)
+}
+
+func TestRedisHook(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	rdb.AddHook(metrics.NewRedisHook())
+
+	ctx := context.Background()
+	_ = rdb.Set(ctx, "k", "v", 0).Err()
+	_ = rdb.Get(ctx, "missing").Err()
+	_ = rdb.Incr(ctx, "k").Err() // 非数字值, 返回错误
+	_, _ = rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Get(ctx, "k")
+		p.Get(ctx, "k")
+		return nil
+	})
+	expectMetrics(t,
+		`redis_command_duration_seconds_count{command="set"} 1`,
+		`redis_command_duration_seconds_count{command="get"} 3`,
+		`redis_command_errors_total{command="incr"} 1`,
+	)
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "root:root@tcp(127.0.0.1:0)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Use(metrics.NewGormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	type User struct {
+		ID   int64
+		Name string
+	}
+	var users []User
+	db.Find(&users)
+	db.Create(&User{Name: "gogo"})
+	expectMetrics(t,
+		`db_query_duration_seconds_count{operation="query",table="users"} 1`,
+		`db_query_duration_seconds_count{operation="create",table="users"} 1`,
+	)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ redis.Hook = (*RedisHook)(nil)
+
+// RedisHook redis 命令耗时及错误数, key 不存在 (redis.Nil) 不计为错误.
+// pipeline 中的命令按命令名分别计数, 耗时记为整个 pipeline 的耗时.
+type RedisHook struct{}
+
+func NewRedisHook() *RedisHook {
+	return &RedisHook{}
+}
+
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		// 命令错误在 hook 返回后才写入 cmd, 使用返回值判断
+		err := next(ctx, cmd)
+		observeRedis(cmd.Name(), err, time.Since(start))
+		return err
+	}
+}
+
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start)
+		for _, cmd := range cmds {
+			observeRedis(cmd.Name(), cmd.Err(), elapsed)
+		}
+		return err
+	}
+}
+
+func observeRedis(name string, err error, elapsed time.Duration) {
+	redisDuration.WithLabelValues(name).Observe(elapsed.Seconds())
+	if err != nil && !errors.Is(err, redis.Nil) {
+		redisErrors.WithLabelValues(name).Inc()
+	}
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理空闲 key 的间隔
+const sweepInterval = time.Minute
+
+// NewTokenBucket 进程内令牌桶, 每 window 补充 limit 个令牌, 桶容量 burst (<= 0 时等于 limit)
+func NewTokenBucket(limit int64, window time.Duration, burst int64) Limiter {
+	if burst <= 0 {
+		burst = limit
+	}
+	return &tokenBucket{
+		rate:    float64(limit) / float64(window),
+		burst:   float64(burst),
+		buckets: newEntries[*bucketState](),
+	}
+}
+
+type tokenBucket struct {
+	rate    float64 // 每纳秒补充的令牌数
+	burst   float64
+	buckets *entries[*bucketState]
+}
+
+type bucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	t := now()
+	b.buckets.mu.Lock()
+	defer b.buckets.mu.Unlock()
+	// 桶补满后与新建的桶等价, 可以清理
+	b.buckets.sweep(t, func(s *bucketState) bool {
+		return float64(t.Sub(s.last))*b.rate+s.tokens >= b.burst
+	})
+	s, ok := b.buckets.m[key]
+	if !ok {
+		s = &bucketState{tokens: b.burst, last: t}
+		b.buckets.m[key] = s
+	}
+	s.tokens = math.Min(b.burst, s.tokens+float64(t.Sub(s.last))*b.rate)
+	s.last = t
+	if s.tokens >= 1 {
+		s.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration(math.Ceil((1 - s.tokens) / b.rate)), nil
+}
+
+// NewSlidingWindow 进程内滑动窗口, 任意 window 时长内最多 limit 个请求.
+// 按当前窗口计数加上一个窗口计数的剩余比例估算, 内存占用与请求量无关.
+func NewSlidingWindow(limit int64, window time.Duration) Limiter {
+	return &slidingWindow{limit: float64(limit), window: window, counters: newEntries[*windowState]()}
+}
+
+type slidingWindow struct {
+	limit    float64
+	window   time.Duration
+	counters *entries[*windowState]
+}
+
+type windowState struct {
+	start     time.Time // 当前窗口开始时间
+	cur, prev float64
+}
+
+func (w *slidingWindow) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	t := now()
+	w.counters.mu.Lock()
+	defer w.counters.mu.Unlock()
+	w.counters.sweep(t, func(s *windowState) bool {
+		return t.Sub(s.start) >= 2*w.window
+	})
+	s, ok := w.counters.m[key]
+	if !ok {
+		s = &windowState{start: t.Truncate(w.window)}
+		w.counters.m[key] = s
+	}
+	if elapsed := t.Sub(s.start); elapsed >= w.window {
+		// 进入新窗口, 超过两个窗口时上一个窗口计数为 0
+		s.prev = 0
+		if elapsed < 2*w.window {
+			s.prev = s.cur
+		}
+		s.cur = 0
+		s.start = s.start.Add(elapsed / w.window * w.window)
+	}
+	elapsed := t.Sub(s.start)
+	weight := 1 - float64(elapsed)/float64(w.window)
+	if s.prev*weight+s.cur+1 <= w.limit {
+		s.cur++
+		return true, 0, nil
+	}
+	// 上一个窗口的权重随时间线性下降, 估算计数降到 limit-1 以下的时间:
+	// 当前窗口内能降下来时按上一个窗口计数估算, 否则等到下一个窗口由当前窗口计数衰减
+	excess := s.prev*weight + s.cur + 1 - w.limit
+	retryAfter := w.window - elapsed
+	if d := time.Duration(excess / s.prev * float64(w.window)); s.prev > 0 && d < retryAfter {
+		retryAfter = d
+	} else if s.cur > 0 {
+		retryAfter += time.Duration(max(0, s.cur+1-w.limit) / s.cur * float64(w.window))
+	}
+	return false, max(retryAfter, time.Millisecond), nil
+}
+
+// entries 按 key 保存限流状态, 定期清理空闲的 key
+type entries[T any] struct {
+	mu        sync.Mutex
+	m         map[string]T
+	lastSweep time.Time
+}
+
+func newEntries[T any]() *entries[T] {
+	return &entries[T]{m: make(map[string]T), lastSweep: now()}
+}
+
+// sweep 调用方持有锁
+func (e *entries[T]) sweep(t time.Time, idle func(T) bool) {
+	if t.Sub(e.lastSweep) < sweepInterval {
+		return
+	}
+	e.lastSweep = t
+	for k, v := range e.m {
+		if idle(v) {
+			delete(e.m, k)
+		}
+	}
+}
+
+// now 当前时间, 测试中替换
+var now = time.Now
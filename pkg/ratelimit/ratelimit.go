@@ -0,0 +1,192 @@
+// Package ratelimit 服务端限流, 超出限制时 grpc 返回 codes.ResourceExhausted, http 返回 429, 并附带 Retry-After.
+//  1. 算法: 令牌桶 (允许突发) 与滑动窗口 (严格限制窗口内请求数).
+//  2. 存储: 进程内存 (单实例限流) 与 redis (多实例共享限额, redis 异常时放行).
+//  3. 规则: 按 grpc 全方法名、http 路由配置, 未配置的接口使用全局规则 (健康检查、指标接口除外); 按客户端 IP、API Key 或自定义 key 分别计数.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+
+	// 内置的计数 key
+	KeyIP     = "ip"      // 客户端 IP
+	KeyAPIKey = "api_key" // 请求头 (grpc metadata) 中的 API Key, 见 Config.APIKeyHeader
+	KeyGlobal = "global"  // 所有客户端共享限额
+
+	defaultAPIKeyHeader = "X-API-Key"
+)
+
+// Limiter 限流器, 实现需并发安全.
+// 拒绝时返回建议的重试等待时间.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Rule 限流规则
+type Rule struct {
+	Target    string        `yaml:"target"`    // grpc 全方法名 /pkg.Service/Method 或 http 路由 "GET /v1/users/:id", 为空时为全局规则
+	Algorithm string        `yaml:"algorithm"` // token_bucket | sliding_window, 默认 token_bucket
+	Limit     int64         `yaml:"limit"`     // 每个窗口允许的请求数, <= 0 时不限流
+	Window    time.Duration `yaml:"window"`    // 默认 1s
+	Burst     int64         `yaml:"burst"`     // token_bucket 桶容量, 默认等于 limit
+	Key       string        `yaml:"key"`       // ip | api_key | global | 自定义 key 名, 默认 ip
+}
+
+type Config struct {
+	Store        string `yaml:"store"`        // memory | redis, 默认 memory; redis 需启用 app.WithRedis
+	APIKeyHeader string `yaml:"apiKeyHeader"` // API Key 请求头, 默认 X-API-Key
+	Rules        []Rule `yaml:"rules"`
+}
+
+type Option func(o *options)
+
+type options struct {
+	httpKeys map[string]func(c *gin.Context) string
+	grpcKeys map[string]func(ctx context.Context) string
+}
+
+// WithHTTPKey 注册自定义 http 计数 key, 规则的 key 为 name 时使用, 如按登录用户计数
+func WithHTTPKey(name string, fn func(c *gin.Context) string) Option {
+	return func(o *options) {
+		o.httpKeys[name] = fn
+	}
+}
+
+// WithGRPCKey 注册自定义 grpc 计数 key, 规则的 key 为 name 时使用
+func WithGRPCKey(name string, fn func(ctx context.Context) string) Option {
+	return func(o *options) {
+		o.grpcKeys[name] = fn
+	}
+}
+
+// Limiters 按规则限流
+type Limiters struct {
+	opts         options
+	apiKeyHeader string
+	global       *rule
+	rules        map[string]*rule
+}
+
+type rule struct {
+	Rule
+	limiter Limiter
+}
+
+// New 按配置创建限流器, store 为 redis 时 client 不能为空; 没有有效规则时返回 nil
+func New(conf Config, client redis.UniversalClient, opts ...Option) (*Limiters, error) {
+	l := &Limiters{apiKeyHeader: conf.APIKeyHeader, rules: make(map[string]*rule)}
+	l.opts = options{
+		httpKeys: make(map[string]func(c *gin.Context) string),
+		grpcKeys: make(map[string]func(ctx context.Context) string),
+	}
+	for _, o := range opts {
+		o(&l.opts)
+	}
+	if l.apiKeyHeader == "" {
+		l.apiKeyHeader = defaultAPIKeyHeader
+	}
+	for _, r := range conf.Rules {
+		if r.Limit <= 0 {
+			continue
+		}
+		if r.Window <= 0 {
+			r.Window = time.Second
+		}
+		if r.Key == "" {
+			r.Key = KeyIP
+		}
+		if !l.validKey(r.Key) {
+			return nil, fmt.Errorf("ratelimit: rule %q: unknown key %q", r.Target, r.Key)
+		}
+		limiter, err := newLimiter(conf.Store, r, client)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: rule %q: %w", r.Target, err)
+		}
+		if r.Target == "" {
+			l.global = &rule{Rule: r, limiter: limiter}
+			continue
+		}
+		l.rules[r.Target] = &rule{Rule: r, limiter: limiter}
+	}
+	if l.global == nil && len(l.rules) == 0 {
+		return nil, nil
+	}
+	return l, nil
+}
+
+func newLimiter(store string, r Rule, client redis.UniversalClient) (Limiter, error) {
+	switch store {
+	case "", StoreMemory:
+		switch r.Algorithm {
+		case "", AlgorithmTokenBucket:
+			return NewTokenBucket(r.Limit, r.Window, r.Burst), nil
+		case AlgorithmSlidingWindow:
+			return NewSlidingWindow(r.Limit, r.Window), nil
+		}
+	case StoreRedis:
+		if client == nil {
+			return nil, fmt.Errorf("redis store requires a redis client")
+		}
+		switch r.Algorithm {
+		case "", AlgorithmTokenBucket:
+			return NewRedisTokenBucket(client, r.Limit, r.Window, r.Burst)
+		case AlgorithmSlidingWindow:
+			return NewRedisSlidingWindow(client, r.Limit, r.Window)
+		}
+	default:
+		return nil, fmt.Errorf("unknown store %q", store)
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", r.Algorithm)
+}
+
+func (l *Limiters) validKey(name string) bool {
+	switch name {
+	case KeyIP, KeyAPIKey, KeyGlobal:
+		return true
+	}
+	_, http := l.opts.httpKeys[name]
+	_, grpc := l.opts.grpcKeys[name]
+	return http || grpc
+}
+
+// match target 的规则, 没有时使用全局规则; 内置接口不受全局规则限制
+func (l *Limiters) match(target string) *rule {
+	if r, ok := l.rules[target]; ok {
+		return r
+	}
+	if builtin(target) {
+		return nil
+	}
+	return l.global
+}
+
+// builtin 健康检查、指标等内置接口. 探针通常来自同一 IP, 共用全局限额时可能被拒绝, 导致健康的实例被重启或摘除
+func builtin(target string) bool {
+	if strings.HasPrefix(target, "/grpc.health.v1.Health/") {
+		return true
+	}
+	_, path, _ := strings.Cut(target, " ")
+	switch path {
+	case "/health", "/livez", "/readyz", "/metrics":
+		return true
+	}
+	return false
+}
+
+// allow 按规则计数, key 以规则区分; limiter 异常时放行
+func (r *rule) allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return r.limiter.Allow(ctx, r.Target+"|"+key)
+}
@@ -0,0 +1,200 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock 替换 now, 返回推进时间的函数
+func fakeClock(t *testing.T) func(d time.Duration) {
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { clock = clock.Add(d) }
+}
+
+func TestLimiters(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	redisTokenBucket, err := NewRedisTokenBucket(client, 2, time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redisSlidingWindow, err := NewRedisSlidingWindow(client, 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		limiter Limiter
+		// 第 3 个请求被拒绝后等待时间的上限
+		retryAfter time.Duration
+	}{
+		{"memory token bucket", NewTokenBucket(2, time.Second, 0), 500 * time.Millisecond},
+		{"redis token bucket", redisTokenBucket, 500 * time.Millisecond},
+		{"memory sliding window", NewSlidingWindow(2, time.Second), 1500 * time.Millisecond},
+		{"redis sliding window", redisSlidingWindow, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advance := fakeClock(t)
+			ctx, key := context.Background(), tt.name
+			for i := 0; i < 2; i++ {
+				if ok, _, err := tt.limiter.Allow(ctx, key); !ok || err != nil {
+					t.Fatalf("request %d: allowed = %v, err = %v", i, ok, err)
+				}
+			}
+			ok, retryAfter, err := tt.limiter.Allow(ctx, key)
+			if ok || err != nil || retryAfter <= 0 || retryAfter > tt.retryAfter {
+				t.Fatalf("allowed = %v, retryAfter = %s, err = %v, want rejected within %s", ok, retryAfter, err, tt.retryAfter)
+			}
+			if ok, _, _ = tt.limiter.Allow(ctx, "other"); !ok {
+				t.Fatal("want keys counted separately")
+			}
+			advance(retryAfter)
+			if ok, _, _ = tt.limiter.Allow(ctx, key); !ok {
+				t.Fatalf("want allowed after %s", retryAfter)
+			}
+		})
+	}
+}
+
+func TestSlidingWindowBoundary(t *testing.T) {
+	advance := fakeClock(t)
+	l := NewSlidingWindow(10, time.Second)
+	ctx := context.Background()
+	// 上一个窗口末尾的请求在下一个窗口开始时仍按比例计入
+	advance(900 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		l.Allow(ctx, "k")
+	}
+	advance(200 * time.Millisecond)
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if ok, _, _ := l.Allow(ctx, "k"); ok {
+			allowed++
+		}
+	}
+	if allowed != 1 {
+		t.Fatalf("allowed = %d, want 1 at 10%% into the next window", allowed)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if l, err := New(Config{}, nil); l != nil || err != nil {
+		t.Fatalf("want nil limiters without rules, got %v %v", l, err)
+	}
+	for _, conf := range []Config{
+		{Store: StoreRedis, Rules: []Rule{{Limit: 1}}},
+		{Store: "etcd", Rules: []Rule{{Limit: 1}}},
+		{Rules: []Rule{{Limit: 1, Algorithm: "leaky"}}},
+		{Rules: []Rule{{Limit: 1, Key: "user"}}},
+	} {
+		if _, err := New(conf, nil); err == nil {
+			t.Errorf("New(%+v) want error", conf)
+		}
+	}
+}
+
+func TestRedisInvalidWindow(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	for _, r := range []Rule{
+		{Limit: 1, Window: time.Microsecond},
+		{Limit: 1, Window: time.Microsecond, Algorithm: AlgorithmSlidingWindow},
+	} {
+		if _, err := New(Config{Store: StoreRedis, Rules: []Rule{r}}, client); err == nil {
+			t.Errorf("rule %+v want error for window below 1ms", r)
+		}
+	}
+	if _, err := NewRedisTokenBucket(client, 0, time.Second, 0); err == nil {
+		t.Error("want error for non-positive limit")
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	fakeClock(t)
+	l, err := New(Config{Rules: []Rule{
+		{Limit: 100},
+		{Target: "POST /login", Limit: 1, Window: time.Minute, Key: KeyAPIKey},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(GinMiddleware(l))
+	e.POST("/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+	e.GET("/readyz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	do := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.Header.Set(defaultAPIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("a"); w.Code != http.StatusOK {
+		t.Fatalf("code = %d, want 200", w.Code)
+	}
+	w := do("a")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("code = %d, Retry-After = %q, want 429 and 60", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w = do("b"); w.Code != http.StatusOK {
+		t.Fatalf("code = %d, want 200 for another api key", w.Code)
+	}
+	// 探针不受全局规则限制
+	for i := 0; i < 200; i++ {
+		w = httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("probe %d: code = %d, want 200", i, w.Code)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	fakeClock(t)
+	l, err := New(Config{Rules: []Rule{
+		{Target: "/svc/Method", Limit: 1, Key: KeyGlobal},
+		{Limit: 1, Key: KeyGlobal},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryServerInterceptor(l)
+	call := func(method string) error {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{})
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(context.Context, any) (any, error) { return nil, nil })
+		return err
+	}
+	if err = call("/svc/Method"); err != nil {
+		t.Fatal(err)
+	}
+	if err = call("/svc/Method"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err = %v, want ResourceExhausted", err)
+	}
+	if err = call("/svc/Other"); err != nil {
+		t.Fatalf("err = %v, want global rule counted separately", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = call("/grpc.health.v1.Health/Check"); err != nil {
+			t.Fatalf("err = %v, want health check exempt from global rule", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/util"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix redis 中限流 key 的前缀
+const keyPrefix = "ratelimit:"
+
+var (
+	// 令牌桶: 按上次更新时间补充令牌, 返回 {是否放行, 等待毫秒数}
+	tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, wait}`)
+	// 滑动窗口: 有序集合记录窗口内的请求时间, 返回 {是否放行, 等待毫秒数}
+	slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+if redis.call("ZCARD", KEYS[1]) < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], window)
+	return {1, 0}
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {0, tonumber(oldest[2]) + window - now}`)
+)
+
+// NewRedisTokenBucket redis 令牌桶, 多实例共享限额, 参数同 NewTokenBucket.
+// 时间取自调用方, 各实例时钟偏差会影响补充速度. 脚本按毫秒计时, window 不能小于 1ms.
+func NewRedisTokenBucket(client redis.UniversalClient, limit int64, window time.Duration, burst int64) (Limiter, error) {
+	if err := validRedisWindow(limit, window); err != nil {
+		return nil, err
+	}
+	if burst <= 0 {
+		burst = limit
+	}
+	return &redisTokenBucket{
+		client: client,
+		rate:   float64(limit) / float64(window.Milliseconds()),
+		burst:  burst,
+	}, nil
+}
+
+type redisTokenBucket struct {
+	client redis.UniversalClient
+	rate   float64 // 每毫秒补充的令牌数
+	burst  int64
+}
+
+func (b *redisTokenBucket) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	res, err := tokenBucketScript.Run(ctx, b.client, []string{keyPrefix + key},
+		strconv.FormatFloat(b.rate, 'f', -1, 64), b.burst, now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return true, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// NewRedisSlidingWindow redis 滑动窗口, 多实例共享限额, 精确记录窗口内每个请求.
+// 内存占用与窗口内请求数成正比, 适合 limit 较小的场景. window 不能小于 1ms.
+func NewRedisSlidingWindow(client redis.UniversalClient, limit int64, window time.Duration) (Limiter, error) {
+	if err := validRedisWindow(limit, window); err != nil {
+		return nil, err
+	}
+	return &redisSlidingWindow{client: client, limit: limit, window: window}, nil
+}
+
+type redisSlidingWindow struct {
+	client redis.UniversalClient
+	limit  int64
+	window time.Duration
+}
+
+func (w *redisSlidingWindow) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	res, err := slidingWindowScript.Run(ctx, w.client, []string{keyPrefix + key},
+		w.limit, w.window.Milliseconds(), now().UnixMilli(), util.UUID()).Int64Slice()
+	if err != nil {
+		return true, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// validRedisWindow redis 脚本以毫秒为单位, 小于 1ms 的窗口会使速率为 +Inf 或 key 立即过期
+func validRedisWindow(limit int64, window time.Duration) error {
+	if limit <= 0 {
+		return fmt.Errorf("limit %d must be positive", limit)
+	}
+	if window < time.Millisecond {
+		return fmt.Errorf("window %s must be at least 1ms", window)
+	}
+	return nil
+}
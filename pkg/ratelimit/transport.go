@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/gogo/web/r"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// codeTooManyRequests 业务状态码, 同 http 429 +1000 的约定
+const codeTooManyRequests r.StatusCode = 4290
+
+// GinMiddleware 按 "METHOD 路由" (如 "GET /v1/users/:id") 匹配规则限流, 超出时返回 429 及 Retry-After.
+// l 为 nil 时不限流.
+func GinMiddleware(l *Limiters) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+		rule := l.match(c.Request.Method + " " + c.FullPath())
+		if rule == nil {
+			c.Next()
+			return
+		}
+		key := l.httpKey(c, rule.Key)
+		ok, retryAfter := l.allow(c.Request.Context(), rule, key)
+		if !ok {
+			c.Header("Retry-After", retrySeconds(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, r.Fail(codeTooManyRequests, "请求过于频繁, 请稍后重试"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// UnaryServerInterceptor 按 grpc 全方法名匹配规则限流, 超出时返回 codes.ResourceExhausted 及 retry-after header.
+// l 为 nil 时不限流.
+func UnaryServerInterceptor(l *Limiters) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		if l == nil {
+			return handler(ctx, req)
+		}
+		if err := l.grpcAllow(ctx, info.FullMethod, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		}); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 同 UnaryServerInterceptor, 在建立流时限流
+func StreamServerInterceptor(l *Limiters) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if l == nil {
+			return handler(srv, ss)
+		}
+		if err := l.grpcAllow(ss.Context(), info.FullMethod, ss.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (l *Limiters) grpcAllow(ctx context.Context, method string, setHeader func(metadata.MD) error) error {
+	rule := l.match(method)
+	if rule == nil {
+		return nil
+	}
+	ok, retryAfter := l.allow(ctx, rule, l.grpcKey(ctx, rule.Key))
+	if ok {
+		return nil
+	}
+	_ = setHeader(metadata.Pairs("retry-after", retrySeconds(retryAfter)))
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %s", retryAfter)
+}
+
+// allow limiter 异常时放行, 避免限流存储故障导致服务不可用
+func (l *Limiters) allow(ctx context.Context, rule *rule, key string) (bool, time.Duration) {
+	ok, retryAfter, err := rule.allow(ctx, key)
+	if err != nil {
+		logger.Errorc(ctx, "rate limit error", "target", rule.Target, "error", err)
+		return true, 0
+	}
+	return ok, retryAfter
+}
+
+// httpKey 计数 key, API Key 缺失时按客户端 IP 计数
+func (l *Limiters) httpKey(c *gin.Context, name string) string {
+	switch name {
+	case KeyGlobal:
+		return KeyGlobal
+	case KeyAPIKey:
+		if key := c.GetHeader(l.apiKeyHeader); key != "" {
+			return KeyAPIKey + ":" + key
+		}
+	case KeyIP:
+	default:
+		if fn, ok := l.opts.httpKeys[name]; ok {
+			return name + ":" + fn(c)
+		}
+	}
+	return KeyIP + ":" + c.ClientIP()
+}
+
+// grpcKey 计数 key, API Key 缺失时按客户端 IP 计数
+func (l *Limiters) grpcKey(ctx context.Context, name string) string {
+	switch name {
+	case KeyGlobal:
+		return KeyGlobal
+	case KeyAPIKey:
+		md, _ := metadata.FromIncomingContext(ctx)
+		if keys := md.Get(strings.ToLower(l.apiKeyHeader)); len(keys) > 0 && keys[0] != "" {
+			return KeyAPIKey + ":" + keys[0]
+		}
+	case KeyIP:
+	default:
+		if fn, ok := l.opts.grpcKeys[name]; ok {
+			return name + ":" + fn(ctx)
+		}
+	}
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return KeyIP + ":" + ip
+}
+
+// retrySeconds Retry-After 秒数, 向上取整且至少 1 秒
+func retrySeconds(d time.Duration) string {
+	return strconv.Itoa(max(int(math.Ceil(d.Seconds())), 1))
+}
@@ -3,8 +3,10 @@ package etcd
 import (
 	"context"
 	"fmt"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
 	"github.com/gogoclouds/project-layout/pkg/registry"
 	"math/rand"
+	"sync"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -39,12 +41,26 @@ func MaxRetry(num int) Option {
 	return func(o *options) { o.maxRetry = num }
 }
 
+var (
+	_ registry.ServiceRegistrar = (*Registry)(nil)
+	_ registry.ServiceUpdater   = (*Registry)(nil)
+	_ registry.BatchDeregistrar = (*Registry)(nil)
+	_ registry.ServiceDiscovery = (*Registry)(nil)
+)
+
 // Registry is etcd registry.
+// 同一个 Registry 可注册多个实例, 每个实例独立的租约和心跳.
 type Registry struct {
 	opts   *options
 	client *clientv3.Client
 	kv     clientv3.KV
 	lease  clientv3.Lease
+
+	mu      sync.Mutex
+	leases  map[string]clientv3.LeaseID   // key -> 当前租约
+	values  map[string]string             // key -> 最新注册的实例信息, 心跳重新注册时使用
+	cancels map[string]context.CancelFunc // key -> 停止心跳
+	dones   map[string]chan struct{}      // key -> 心跳协程退出时关闭
 }
 
 // New creates etcd registry
@@ -59,42 +75,135 @@ func New(client *clientv3.Client, opts ...Option) (r *Registry) {
 		o(op)
 	}
 	return &Registry{
-		opts:   op,
-		client: client,
-		kv:     clientv3.NewKV(client),
+		opts:    op,
+		client:  client,
+		kv:      clientv3.NewKV(client),
+		lease:   clientv3.NewLease(client),
+		leases:  make(map[string]clientv3.LeaseID),
+		values:  make(map[string]string),
+		cancels: make(map[string]context.CancelFunc),
+		dones:   make(map[string]chan struct{}),
 	}
 }
 
 // Registry the registration.
 func (r *Registry) Registry(ctx context.Context, service *registry.ServiceInstance) error {
-	key := fmt.Sprintf("%s/%s/%s", r.opts.namespace, service.Name, service.ID)
+	key := r.serviceKey(service)
 	value, err := marshal(service)
 	if err != nil {
 		return err
 	}
-	if r.lease != nil {
-		r.lease.Close()
+	// 重复注册同一实例时停止旧的心跳
+	_, done := r.stopHeartBeat(key)
+	if err = waitHeartBeat(ctx, done); err != nil {
+		return err
 	}
-	r.lease = clientv3.NewLease(r.client)
 	leaseID, err := r.registerWithKV(ctx, key, value)
 	if err != nil {
 		return err
 	}
+	hctx, cancel := context.WithCancel(r.opts.ctx)
+	done = make(chan struct{})
+	r.mu.Lock()
+	r.values[key] = value
+	r.cancels[key] = cancel
+	r.dones[key] = done
+	r.mu.Unlock()
 
-	go r.heartBeat(r.opts.ctx, leaseID, key, value)
+	go func() {
+		defer close(done)
+		r.heartBeat(hctx, leaseID, key, value, service.Name)
+	}()
+	return nil
+}
+
+// Update 使用当前租约原地更新已注册的实例信息
+func (r *Registry) Update(ctx context.Context, service *registry.ServiceInstance) error {
+	key := r.serviceKey(service)
+	value, err := marshal(service)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	leaseID := r.leases[key]
+	_, registered := r.values[key]
+	r.mu.Unlock()
+	if !registered || leaseID == 0 {
+		return fmt.Errorf("service %s not registered", key)
+	}
+	if _, err = r.client.Put(ctx, key, value, clientv3.WithLease(leaseID)); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.values[key] = value
+	r.mu.Unlock()
 	return nil
 }
 
 // Deregister the registration.
 func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
-	defer func() {
-		if r.lease != nil {
-			r.lease.Close()
+	return r.BatchDeregister(ctx, service)
+}
+
+// BatchDeregister 在一个事务中删除多个实例, 保证这些实例同时下线
+func (r *Registry) BatchDeregister(ctx context.Context, services ...*registry.ServiceInstance) error {
+	ops := make([]clientv3.Op, 0, len(services))
+	leases := make([]clientv3.LeaseID, 0, len(services))
+	dones := make([]chan struct{}, 0, len(services))
+	for _, service := range services {
+		key := r.serviceKey(service)
+		ops = append(ops, clientv3.OpDelete(key))
+		leaseID, done := r.stopHeartBeat(key)
+		if leaseID != 0 {
+			leases = append(leases, leaseID)
 		}
-	}()
-	key := fmt.Sprintf("%s/%s/%s", r.opts.namespace, service.Name, service.ID)
-	_, err := r.client.Delete(ctx, key)
-	return err
+		dones = append(dones, done)
+	}
+	// 等待心跳协程退出后再删除, 避免重新注册的实例在删除后又写回
+	for _, done := range dones {
+		if err := waitHeartBeat(ctx, done); err != nil {
+			return err
+		}
+	}
+	if _, err := r.client.Txn(ctx).Then(ops...).Commit(); err != nil {
+		return err
+	}
+	for _, leaseID := range leases {
+		_, _ = r.lease.Revoke(ctx, leaseID)
+	}
+	return nil
+}
+
+func (r *Registry) serviceKey(service *registry.ServiceInstance) string {
+	return fmt.Sprintf("%s/%s/%s", r.opts.namespace, service.Name, service.ID)
+}
+
+// stopHeartBeat 停止实例心跳并清理状态, 返回实例的租约和心跳协程退出的通知, 未注册时 done 为 nil
+func (r *Registry) stopHeartBeat(key string) (clientv3.LeaseID, chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.cancels[key]; ok {
+		cancel()
+	}
+	leaseID, done := r.leases[key], r.dones[key]
+	delete(r.cancels, key)
+	delete(r.dones, key)
+	delete(r.leases, key)
+	delete(r.values, key)
+	return leaseID, done
+}
+
+// waitHeartBeat 等待心跳协程退出
+func waitHeartBeat(ctx context.Context, done chan struct{}) error {
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // GetService return the service instances in memory according to the service name.
@@ -124,20 +233,37 @@ func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, er
 	return newWatcher(ctx, key, name, r.client)
 }
 
-// registerWithKV create a new lease, return current leaseID
+// registerWithKV create a new lease, return current leaseID.
+// 写入和记录租约在 r.mu 内进行, 与 stopHeartBeat 互斥, 心跳已停止 (ctx 取消) 时不再写入
 func (r *Registry) registerWithKV(ctx context.Context, key string, value string) (clientv3.LeaseID, error) {
 	grant, err := r.lease.Grant(ctx, int64(r.opts.ttl.Seconds()))
 	if err != nil {
 		return 0, err
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
 	_, err = r.client.Put(ctx, key, value, clientv3.WithLease(grant.ID))
 	if err != nil {
 		return 0, err
 	}
+	r.leases[key] = grant.ID
 	return grant.ID, nil
 }
 
-func (r *Registry) heartBeat(ctx context.Context, leaseID clientv3.LeaseID, key string, value string) {
+// latestValue 返回 Update 后的最新实例信息, 未更新过则返回 value
+func (r *Registry) latestValue(key, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.values[key]; ok {
+		return v
+	}
+	return value
+}
+
+func (r *Registry) heartBeat(ctx context.Context, leaseID clientv3.LeaseID, key, value, name string) {
 	curLeaseID := leaseID
 	kac, err := r.client.KeepAlive(ctx, leaseID)
 	if err != nil {
@@ -153,13 +279,14 @@ func (r *Registry) heartBeat(ctx context.Context, leaseID clientv3.LeaseID, key
 				if ctx.Err() != nil {
 					return
 				}
+				metrics.RegistryRetry(name)
 				// prevent infinite blocking
 				idChan := make(chan clientv3.LeaseID, 1)
 				errChan := make(chan error, 1)
 				cancelCtx, cancel := context.WithCancel(ctx)
 				go func() {
 					defer cancel()
-					id, registerErr := r.registerWithKV(cancelCtx, key, value)
+					id, registerErr := r.registerWithKV(cancelCtx, key, r.latestValue(key, value))
 					if registerErr != nil {
 						errChan <- registerErr
 					} else {
@@ -181,10 +308,22 @@ func (r *Registry) heartBeat(ctx context.Context, leaseID clientv3.LeaseID, key
 					break
 				}
 				retreat = append(retreat, 1<<retryCnt)
-				time.Sleep(time.Duration(retreat[rand.Intn(len(retreat))]) * time.Second)
+				select {
+				case <-time.After(time.Duration(retreat[rand.Intn(len(retreat))]) * time.Second):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if kac == nil {
+				metrics.RegistryFailure(name)
+				return
 			}
 			if _, ok := <-kac; !ok {
 				// retry failed
+				metrics.RegistryFailure(name)
 				return
 			}
 		}
@@ -200,7 +339,7 @@ func (r *Registry) heartBeat(ctx context.Context, leaseID clientv3.LeaseID, key
 				curLeaseID = 0
 				continue
 			}
-		case <-r.opts.ctx.Done():
+		case <-ctx.Done():
 			return
 		}
 	}
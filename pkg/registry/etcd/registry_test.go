@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/gogoclouds/project-layout/pkg/registry"
+	"sync"
 	"testing"
 	"time"
 
@@ -140,7 +141,7 @@ func TestHeartBeat(t *testing.T) {
 		t.Errorf("not expected empty")
 	}
 
-	go r.heartBeat(ctx, leaseID, key, value)
+	go r.heartBeat(ctx, leaseID, key, value, s.Name)
 
 	time.Sleep(time.Second)
 	res, err = r.GetService(ctx, s.Name)
@@ -151,3 +152,69 @@ func TestHeartBeat(t *testing.T) {
 		t.Errorf("reconnect failed")
 	}
 }
+
+// blockingLease 阻塞 Grant 直到 release 关闭, 模拟下线时心跳正在重新注册
+type blockingLease struct {
+	clientv3.Lease
+	granting chan struct{}
+	release  chan struct{}
+	once     sync.Once
+}
+
+func (l *blockingLease) Grant(_ context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	l.once.Do(func() { close(l.granting) })
+	<-l.release
+	return l.Lease.Grant(context.Background(), ttl)
+}
+
+// TestDeregisterDuringReRegister 心跳重新注册期间下线, 实例不能被写回
+func TestDeregisterDuringReRegister(t *testing.T) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{"127.0.0.1:2379"},
+		DialTimeout: time.Second, DialOptions: []grpc.DialOption{grpc.WithBlock()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	r := New(client, RegisterTTL(2*time.Second))
+	s := &registry.ServiceInstance{ID: "0", Name: "helloworld"}
+	key := r.serviceKey(s)
+	if err = r.Registry(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	lease := &blockingLease{Lease: r.lease, granting: make(chan struct{}), release: make(chan struct{})}
+	r.mu.Lock()
+	leaseID, done := r.leases[key], r.dones[key]
+	r.lease = lease
+	r.mu.Unlock()
+
+	// 撤销租约使心跳进入重新注册, 在申请租约时下线
+	if _, err = client.Revoke(ctx, leaseID); err != nil {
+		t.Fatal(err)
+	}
+	<-lease.granting
+	errCh := make(chan error, 1)
+	go func() { errCh <- r.Deregister(ctx, s) }()
+	time.Sleep(100 * time.Millisecond)
+	close(lease.release)
+	if err = <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	default:
+		t.Fatal("heartbeat still running after deregister")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 0 {
+		t.Fatal("instance written back after deregister")
+	}
+}
@@ -12,6 +12,16 @@ type ServiceRegistrar interface {
 	Deregister(ctx context.Context, service *ServiceInstance) error
 }
 
+// ServiceUpdater 可选接口: 不重建租约, 原地更新已注册的实例 (如运行时修改元数据)
+type ServiceUpdater interface {
+	Update(ctx context.Context, service *ServiceInstance) error
+}
+
+// BatchDeregistrar 可选接口: 原子地注销多个实例 (同一进程内的多个逻辑服务同时下线)
+type BatchDeregistrar interface {
+	BatchDeregister(ctx context.Context, services ...*ServiceInstance) error
+}
+
 // ServiceDiscovery 服务发现
 // 1.本地缓存 (不需要每次请求服务,都去注册中心拿取)
 // 2.与注册中心长连接
@@ -37,3 +47,16 @@ type ServiceInstance struct {
 	// grpc://127.0.0.1:9000
 	Endpoints []string `json:"endpoints"`
 }
+
+// 实例元数据常用 key
+const (
+	MetadataEnv       = "env"          // 运行环境 dev | test | prod
+	MetadataHostname  = "hostname"     // 主机名
+	MetadataStartTime = "start_time"   // 启动时间 RFC3339
+	MetadataBuild     = "build"        // 构建版本 (go module version)
+	MetadataRevision  = "vcs_revision" // git commit
+	MetadataGoVersion = "go_version"   // 编译使用的 go 版本
+	MetadataWeight    = "weight"       // 负载均衡权重
+	MetadataZone      = "zone"         // 可用区
+	MetadataDraining  = "draining"     // "true" 表示实例正在下线, 不再接收新请求
+)
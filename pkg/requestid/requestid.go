@@ -0,0 +1,132 @@
+// Package requestid 请求 ID 的生成与传递.
+// 入口 (http、rpc 服务) 读取上游的 X-Request-ID, 没有或不合法时生成新的 ID,
+// 写入 ctx (见 logger.RequestID) 并回写到 http 响应头、rpc trailer; 出口 (rpc、http 客户端) 将 ctx 中的 ID 传给下游.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	Header      = "X-Request-ID" // http 请求头、响应头
+	MetadataKey = "x-request-id" // grpc metadata、trailer
+
+	maxLength = 128
+)
+
+// FromContext 从 ctx 中获取请求 ID
+func FromContext(ctx context.Context) string {
+	return logger.RequestID(ctx)
+}
+
+// GinMiddleware 读取或生成请求 ID, 写入 request context 及响应头
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := ensure(c.GetHeader(Header))
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+// UnaryServerInterceptor 读取或生成请求 ID, 写入 ctx 及 trailer
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		id := ensure(fromIncoming(ctx))
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(MetadataKey, id))
+		return handler(logger.WithRequestID(ctx, id), req)
+	}
+}
+
+// StreamServerInterceptor 读取或生成请求 ID, 写入 stream ctx 及 trailer
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		id := ensure(fromIncoming(ss.Context()))
+		ss.SetTrailer(metadata.Pairs(MetadataKey, id))
+		return handler(srv, &serverStream{ServerStream: ss, ctx: logger.WithRequestID(ss.Context(), id)})
+	}
+}
+
+// UnaryClientInterceptor 将 ctx 中的请求 ID 传给下游
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(toOutgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor 将 ctx 中的请求 ID 传给下游
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(toOutgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+// Transport 将 ctx 中的请求 ID 写入 http 请求头传给下游
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		if id := FromContext(req.Context()); id != "" && req.Header.Get(Header) == "" {
+			req = req.Clone(req.Context())
+			req.Header.Set(Header, id)
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripper func(req *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func fromIncoming(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(MetadataKey); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func toOutgoing(ctx context.Context) context.Context {
+	id := logger.RequestID(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(MetadataKey)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, id)
+}
+
+// ensure 上游 ID 为空或不合法 (过长、包含不可见字符) 时生成新的 ID, 避免日志注入
+func ensure(id string) string {
+	if id == "" || len(id) > maxLength {
+		return util.UUID()
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return util.UUID()
+		}
+	}
+	return id
+}
@@ -0,0 +1,112 @@
+package requestid_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/pkg/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(requestid.GinMiddleware())
+	var got string
+	e.GET("/ping", func(c *gin.Context) {
+		got = requestid.FromContext(c.Request.Context())
+	})
+
+	for _, tc := range []struct {
+		header string
+		keep   bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"bad\nid", false},
+		{strings.Repeat("x", 200), false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if tc.header != "" {
+			req.Header.Set(requestid.Header, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if got == "" || rec.Header().Get(requestid.Header) != got {
+			t.Fatalf("request id %q not echoed: %q", got, rec.Header().Get(requestid.Header))
+		}
+		if (got == tc.header) != tc.keep {
+			t.Fatalf("header %q: request id %q", tc.header, got)
+		}
+	}
+}
+
+// idServer 记录 handler 中 ctx 的请求 ID
+type idServer struct {
+	healthgrpc.UnimplementedHealthServer
+	got chan string
+}
+
+func (s *idServer) Check(ctx context.Context, _ *healthgrpc.HealthCheckRequest) (*healthgrpc.HealthCheckResponse, error) {
+	s.got <- requestid.FromContext(ctx)
+	return &healthgrpc.HealthCheckResponse{Status: healthgrpc.HealthCheckResponse_SERVING}, nil
+}
+
+func TestGrpc(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(requestid.UnaryServerInterceptor()))
+	is := &idServer{got: make(chan string, 2)}
+	healthgrpc.RegisterHealthServer(srv, is)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(requestid.UnaryClientInterceptor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthgrpc.NewHealthClient(conn)
+
+	// 上游请求 ID 透传给下游, 并回写到 trailer
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(requestid.GinMiddleware())
+	var trailer metadata.MD
+	e.GET("/call", func(c *gin.Context) {
+		_, err = client.Check(c.Request.Context(), &healthgrpc.HealthCheckRequest{}, grpc.Trailer(&trailer))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/call", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := <-is.got; got != "req-1" {
+		t.Fatalf("request id not forwarded: %q", got)
+	}
+	if v := trailer.Get(requestid.MetadataKey); len(v) != 1 || v[0] != "req-1" {
+		t.Fatalf("unexpected trailer: %v", trailer)
+	}
+
+	// 没有上游请求 ID 时生成
+	if _, err = client.Check(context.Background(), &healthgrpc.HealthCheckRequest{}, grpc.Trailer(&trailer)); err != nil {
+		t.Fatal(err)
+	}
+	got := <-is.got
+	if got == "" || trailer.Get(requestid.MetadataKey)[0] != got {
+		t.Fatalf("request id %q not generated or echoed: %v", got, trailer)
+	}
+}
@@ -0,0 +1,55 @@
+package selector
+
+import (
+	"context"
+
+	"github.com/gogoclouds/project-layout/pkg/registry"
+)
+
+// Version 只保留版本满足约束的节点, 约束语法见 ParseConstraint
+func Version(constraint string) (Filter, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ context.Context, nodes []*Node) []*Node {
+		return filter(nodes, func(n *Node) bool {
+			return n.Instance != nil && c.Check(n.Instance.Version)
+		})
+	}, nil
+}
+
+// Metadata 只保留实例元数据 key 等于 value 的节点
+func Metadata(key, value string) Filter {
+	return func(_ context.Context, nodes []*Node) []*Node {
+		return filter(nodes, func(n *Node) bool {
+			return n.Instance != nil && n.Instance.Metadata[key] == value
+		})
+	}
+}
+
+// Env 只保留同一运行环境的节点
+func Env(env string) Filter {
+	return Metadata(registry.MetadataEnv, env)
+}
+
+// Zone 优先同可用区的节点, 同可用区没有节点时不过滤
+func Zone(zone string) Filter {
+	same := Metadata(registry.MetadataZone, zone)
+	return func(ctx context.Context, nodes []*Node) []*Node {
+		if filtered := same(ctx, nodes); len(filtered) > 0 {
+			return filtered
+		}
+		return nodes
+	}
+}
+
+func filter(nodes []*Node, keep func(n *Node) bool) []*Node {
+	filtered := make([]*Node, 0, len(nodes))
+	for _, n := range nodes {
+		if keep(n) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
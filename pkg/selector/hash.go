@@ -0,0 +1,72 @@
+package selector
+
+import (
+	"context"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// replicas 默认权重节点的虚拟节点数
+const replicas = 160
+
+// NewConsistentHash 一致性哈希, 按 WithHashKey 设置的 key 选择节点, 相同 key 在节点不变时总是选中同一节点.
+// 虚拟节点数按权重等比例分配, 未设置 key 时返回 ErrNoHashKey.
+func NewConsistentHash() Balancer {
+	return &consistentHash{}
+}
+
+type consistentHash struct {
+	mu   sync.Mutex
+	sig  string // 节点签名, 节点变化时重建哈希环
+	ring []ringEntry
+}
+
+type ringEntry struct {
+	hash uint32
+	node *Node
+}
+
+func (b *consistentHash) Pick(ctx context.Context, nodes []*Node) (*Node, DoneFunc, error) {
+	key := HashKey(ctx)
+	if key == "" {
+		return nil, nil, ErrNoHashKey
+	}
+	ring := b.build(nodes)
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+	return ring[i].node, noopDone, nil
+}
+
+func (b *consistentHash) build(nodes []*Node) []ringEntry {
+	var sb strings.Builder
+	for _, n := range nodes {
+		sb.WriteString(n.Address)
+		sb.WriteByte('/')
+		sb.WriteString(strconv.Itoa(n.Weight()))
+		sb.WriteByte(',')
+	}
+	sig := sb.String()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if sig == b.sig {
+		return b.ring
+	}
+	var ring []ringEntry
+	for _, n := range nodes {
+		count := max(replicas*n.Weight()/DefaultWeight, 1)
+		for i := 0; i < count; i++ {
+			h := crc32.ChecksumIEEE([]byte(n.Address + "#" + strconv.Itoa(i)))
+			ring = append(ring, ringEntry{hash: h, node: n})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	b.sig, b.ring = sig, ring
+	return ring
+}
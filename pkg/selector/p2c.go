@@ -0,0 +1,115 @@
+package selector
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// decayTime EWMA 衰减时间, 越久之前的延迟权重越低
+	decayTime = 10 * time.Second
+	// forcePick 节点超过该时间未被选中时强制选择一次, 刷新其延迟统计
+	forcePick = 3 * time.Second
+	// errorPenalty 请求失败时计入的最小延迟
+	errorPenalty = time.Second
+)
+
+// NewP2C 两次随机选择 (power of two choices): 随机取两个节点, 选择负载较低的一个.
+// 负载 = EWMA 延迟 × (进行中请求数 + 1), 未被选择过的节点负载为 0, 优先被探测.
+func NewP2C() Balancer {
+	return &p2c{
+		stats: make(map[string]*nodeStats),
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+type p2c struct {
+	mu    sync.Mutex
+	stats map[string]*nodeStats
+	rand  *rand.Rand
+}
+
+type nodeStats struct {
+	lag      float64 // EWMA 延迟, 纳秒
+	inflight int64
+	last     time.Time // 上次更新延迟
+	picked   time.Time // 上次被选中
+}
+
+func (s *nodeStats) load() float64 {
+	return s.lag * float64(s.inflight+1)
+}
+
+func (b *p2c) Pick(_ context.Context, nodes []*Node) (*Node, DoneFunc, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.stats) > len(nodes)*2 {
+		// 清理已下线节点
+		alive := make(map[string]*nodeStats, len(nodes))
+		for _, n := range nodes {
+			if s, ok := b.stats[n.Address]; ok {
+				alive[n.Address] = s
+			}
+		}
+		b.stats = alive
+	}
+
+	t := now()
+	var picked *Node
+	if len(nodes) == 1 {
+		picked = nodes[0]
+	} else {
+		i := b.rand.Intn(len(nodes))
+		j := b.rand.Intn(len(nodes) - 1)
+		if j >= i {
+			j++
+		}
+		a, c := nodes[i], nodes[j]
+		sa, sc := b.nodeStats(a.Address), b.nodeStats(c.Address)
+		if sa.load() > sc.load() {
+			a, c, sa, sc = c, a, sc, sa
+		}
+		picked = a
+		if t.Sub(sc.picked) > forcePick {
+			picked = c
+		}
+	}
+
+	s := b.nodeStats(picked.Address)
+	s.inflight++
+	s.picked = t
+	return picked, func(_ context.Context, info DoneInfo) {
+		b.done(s, t, info)
+	}, nil
+}
+
+func (b *p2c) nodeStats(addr string) *nodeStats {
+	s, ok := b.stats[addr]
+	if !ok {
+		s = &nodeStats{}
+		b.stats[addr] = s
+	}
+	return s
+}
+
+func (b *p2c) done(s *nodeStats, start time.Time, info DoneInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := now()
+	lag := t.Sub(start)
+	if info.Err != nil && lag < errorPenalty {
+		lag = errorPenalty
+	}
+	s.inflight--
+	if s.last.IsZero() {
+		s.lag = float64(lag)
+	} else {
+		// 距上次更新越久, 旧值权重越低
+		w := math.Exp(-float64(t.Sub(s.last)) / float64(decayTime))
+		s.lag = s.lag*w + float64(lag)*(1-w)
+	}
+	s.last = t
+}
@@ -0,0 +1,120 @@
+// Package selector 客户端负载均衡: 按过滤器筛选服务实例后由 Balancer 选出一个节点.
+// 可用于 grpc 客户端 (见 client/grpc.RegisterBalancer) 及 http 客户端.
+//
+//	filter, _ := selector.Version(">=1.2.0 <2.0.0")
+//	s := selector.New(selector.NewWeightedRoundRobin(), filter, selector.Env("prod"))
+//	node, done, err := s.Select(ctx, selector.Nodes(instances, "grpc"))
+package selector
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/registry"
+)
+
+// DefaultWeight 未设置或设置了无效的 registry.MetadataWeight 时的权重
+const DefaultWeight = 100
+
+var (
+	ErrNoAvailable = errors.New("selector: no available node")
+	ErrNoHashKey   = errors.New("selector: no hash key in context")
+)
+
+// Node 候选节点, 一个实例的一个地址
+type Node struct {
+	Address  string // host:port
+	Instance *registry.ServiceInstance
+}
+
+// Weight 实例元数据 registry.MetadataWeight 中的权重
+func (n *Node) Weight() int {
+	if n.Instance == nil {
+		return DefaultWeight
+	}
+	w, err := strconv.Atoi(n.Instance.Metadata[registry.MetadataWeight])
+	if err != nil || w <= 0 {
+		return DefaultWeight
+	}
+	return w
+}
+
+// Nodes 取出实例中指定 scheme 的地址, 跳过正在下线 (registry.MetadataDraining) 的实例, 按地址排序并去重
+func Nodes(instances []*registry.ServiceInstance, scheme string) []*Node {
+	seen := make(map[string]bool, len(instances))
+	nodes := make([]*Node, 0, len(instances))
+	for _, ins := range instances {
+		if ins.Metadata[registry.MetadataDraining] == "true" {
+			continue
+		}
+		for _, ep := range ins.Endpoints {
+			u, err := url.Parse(ep)
+			if err != nil || u.Scheme != scheme || u.Host == "" || seen[u.Host] {
+				continue
+			}
+			seen[u.Host] = true
+			nodes = append(nodes, &Node{Address: u.Host, Instance: ins})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Address < nodes[j].Address })
+	return nodes
+}
+
+// DoneInfo 请求结束信息
+type DoneInfo struct {
+	Err error
+}
+
+// DoneFunc 请求结束时回调, 用于统计延迟、错误
+type DoneFunc func(ctx context.Context, info DoneInfo)
+
+// Balancer 负载均衡策略, 实现需并发安全. nodes 非空且按地址有序.
+type Balancer interface {
+	Pick(ctx context.Context, nodes []*Node) (*Node, DoneFunc, error)
+}
+
+// Filter 节点过滤器, 返回满足条件的节点
+type Filter func(ctx context.Context, nodes []*Node) []*Node
+
+// Selector 依次应用过滤器后由 Balancer 选出节点
+type Selector struct {
+	balancer Balancer
+	filters  []Filter
+}
+
+func New(balancer Balancer, filters ...Filter) *Selector {
+	return &Selector{balancer: balancer, filters: filters}
+}
+
+// Select 选出一个节点, 请求结束后需调用 DoneFunc
+func (s *Selector) Select(ctx context.Context, nodes []*Node) (*Node, DoneFunc, error) {
+	for _, f := range s.filters {
+		nodes = f(ctx, nodes)
+	}
+	if len(nodes) == 0 {
+		return nil, nil, ErrNoAvailable
+	}
+	return s.balancer.Pick(ctx, nodes)
+}
+
+type hashKey struct{}
+
+// WithHashKey 一致性哈希使用的请求 key, 如用户 ID
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKey{}, key)
+}
+
+// HashKey 取出 WithHashKey 设置的 key
+func HashKey(ctx context.Context) string {
+	key, _ := ctx.Value(hashKey{}).(string)
+	return key
+}
+
+func noopDone(context.Context, DoneInfo) {}
+
+// now 当前时间, 测试中替换
+var now = time.Now
@@ -0,0 +1,206 @@
+package selector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/registry"
+)
+
+func node(addr string, md map[string]string) *Node {
+	return &Node{Address: addr, Instance: &registry.ServiceInstance{Name: "svc", Metadata: md}}
+}
+
+func TestNodes(t *testing.T) {
+	instances := []*registry.ServiceInstance{
+		{ID: "2", Endpoints: []string{"grpc://10.0.0.2:9000", "http://10.0.0.2:8000"}},
+		{ID: "1", Endpoints: []string{"grpc://10.0.0.1:9000", "grpc://10.0.0.1:9000"}},
+		{ID: "3", Endpoints: []string{"grpc://10.0.0.3:9000"}, Metadata: map[string]string{registry.MetadataDraining: "true"}},
+	}
+	var got []string
+	for _, n := range Nodes(instances, "grpc") {
+		got = append(got, n.Address)
+	}
+	if want := "10.0.0.1:9000,10.0.0.2:9000"; strings.Join(got, ",") != want {
+		t.Fatalf("nodes = %v, want %s", got, want)
+	}
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	nodes := []*Node{
+		node("a", map[string]string{registry.MetadataWeight: "5"}),
+		node("b", map[string]string{registry.MetadataWeight: "1"}),
+		node("c", map[string]string{registry.MetadataWeight: "1"}),
+	}
+	b := NewWeightedRoundRobin()
+	var got []string
+	for i := 0; i < 14; i++ {
+		n, _, _ := b.Pick(context.Background(), nodes)
+		got = append(got, n.Address)
+	}
+	if want := "aabacaaaabacaa"; strings.Join(got, "") != want {
+		t.Fatalf("picks = %s, want %s", strings.Join(got, ""), want)
+	}
+}
+
+func TestP2C(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	b := &p2c{stats: make(map[string]*nodeStats), rand: rand.New(rand.NewSource(1))}
+	nodes := []*Node{node("fast", nil), node("slow", nil)}
+	latency := map[string]time.Duration{"fast": 10 * time.Millisecond, "slow": 200 * time.Millisecond}
+	picks := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		n, done, err := b.Pick(context.Background(), nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picks[n.Address]++
+		clock = clock.Add(latency[n.Address])
+		done(context.Background(), DoneInfo{})
+	}
+	// 慢节点只在探测及强制选择时被选中
+	if picks["slow"] > 15 || picks["fast"] < 85 {
+		t.Fatalf("picks = %v, want most on fast", picks)
+	}
+
+	// 失败请求按 errorPenalty 计入延迟
+	s := b.stats["fast"]
+	n, done, _ := b.Pick(context.Background(), []*Node{nodes[0]})
+	if n.Address != "fast" || s.inflight != 1 {
+		t.Fatalf("inflight = %d, want 1", s.inflight)
+	}
+	before := s.lag
+	clock = clock.Add(time.Millisecond)
+	done(context.Background(), DoneInfo{Err: errors.New("unavailable")})
+	if s.inflight != 0 || s.lag <= before {
+		t.Fatalf("lag = %v, want greater than %v after error", s.lag, before)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	b := NewConsistentHash()
+	nodes := []*Node{node("a", nil), node("b", nil), node("c", nil)}
+	if _, _, err := b.Pick(context.Background(), nodes); !errors.Is(err, ErrNoHashKey) {
+		t.Fatalf("err = %v, want ErrNoHashKey", err)
+	}
+
+	pick := func(nodes []*Node, key string) string {
+		n, _, err := b.Pick(WithHashKey(context.Background(), key), nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n.Address
+	}
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		before[key] = pick(nodes, key)
+		if pick(nodes, key) != before[key] {
+			t.Fatalf("key %s mapped to different nodes", key)
+		}
+		counts[before[key]]++
+	}
+	for _, n := range nodes {
+		if counts[n.Address] < 200 {
+			t.Errorf("counts = %v, want roughly even", counts)
+		}
+	}
+
+	// 移除节点 c, 只有原本落在 c 上的 key 会迁移
+	for key, addr := range before {
+		if addr != "c" && pick(nodes[:2], key) != addr {
+			t.Fatalf("key %s moved from %s after removing c", key, addr)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"1.2.0", "v1.2.0", true},
+		{">=1.2.0 <2.0.0", "1.9.9", true},
+		{">=1.2.0 <2.0.0", "2.0.0", false},
+		{"^1.2.0", "1.3.0", true},
+		{"^1.2.0", "2.0.0", false},
+		{"~1.2.0", "1.2.9", true},
+		{"~1.2.0", "1.3.0", false},
+		{">=1.2.0", "1.2.0-rc.1", false},
+		{"!=1.2.0", "1.2.1", true},
+		{"<1.0.0 || >=3", "3.1.0", true},
+		{"<1.0.0 || >=3", "2.0.0", false},
+		{">=1.0.0", "invalid", false},
+		// 构建元数据不参与比较, 其中的 - 不是预发布版本
+		{"1.2.3", "1.2.3+build-1", true},
+		{">=1.2.3", "1.2.3-rc.1+build-1", false},
+		// 预发布标识符逐个比较, 数字按数值
+		{">1.0.0-rc.9", "1.0.0-rc.10", true},
+		{">1.0.0-rc.1", "1.0.0-rc.1.1", true},
+		{">1.0.0-alpha.beta", "1.0.0-alpha.1", false},
+		{">1.0.0-alpha", "1.0.0-beta", true},
+		// 0.x 的 ^ 不改变最左侧的非零版本号
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"^0.0", "0.0.9", true},
+		{"^0.0", "0.1.0", false},
+		{"^0", "0.9.0", true},
+		{"~1", "1.9.0", true},
+		{"~1", "2.0.0", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Check(tt.version); got != tt.want {
+			t.Errorf("%q check %q = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", ">=x.1", "1.2.3.4", "1 ||"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("ParseConstraint(%q) want error", bad)
+		}
+	}
+}
+
+func TestSelectorFilters(t *testing.T) {
+	nodes := []*Node{
+		node("a", map[string]string{registry.MetadataEnv: "prod", registry.MetadataZone: "z1"}),
+		node("b", map[string]string{registry.MetadataEnv: "prod", registry.MetadataZone: "z2"}),
+		node("c", map[string]string{registry.MetadataEnv: "test", registry.MetadataZone: "z1"}),
+	}
+	nodes[0].Instance.Version = "1.0.0"
+	nodes[1].Instance.Version = "2.0.0"
+	nodes[2].Instance.Version = "2.1.0"
+	version, err := Version(">=2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(NewWeightedRoundRobin(), version, Env("prod"))
+	n, _, err := s.Select(context.Background(), nodes)
+	if err != nil || n.Address != "b" {
+		t.Fatalf("select = %v %v, want b", n, err)
+	}
+	// z1 没有满足条件的节点, 不按可用区过滤
+	s = New(NewWeightedRoundRobin(), version, Env("prod"), Zone("z1"))
+	if n, _, _ = s.Select(context.Background(), nodes); n.Address != "b" {
+		t.Fatalf("select = %s, want b", n.Address)
+	}
+	s = New(NewWeightedRoundRobin(), Env("staging"))
+	if _, _, err = s.Select(context.Background(), nodes); !errors.Is(err, ErrNoAvailable) {
+		t.Fatalf("err = %v, want ErrNoAvailable", err)
+	}
+}
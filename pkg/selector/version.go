@@ -0,0 +1,200 @@
+package selector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint 版本约束
+type Constraint struct {
+	groups [][]comparator // 组间为或, 组内为且
+}
+
+type comparator struct {
+	op string
+	v  version
+}
+
+// ParseConstraint 解析版本约束, 版本格式为 [v]MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD], 按 semver 规则比较.
+//   - 比较: =1.2.0、!=1.2.0、>1.2.0、>=1.2.0、<2.0.0、<=2.0.0, 省略运算符等同于 =
+//   - ^ 不改变最左侧的非零版本号: ^1.2.0 等同于 >=1.2.0 <2.0.0, ^0.2.0 等同于 >=0.2.0 <0.3.0, ^0.0.3 等同于 >=0.0.3 <0.0.4
+//   - ~ 只允许 PATCH 变化: ~1.2.0 等同于 >=1.2.0 <1.3.0, 只给出 MAJOR 时 ~1 等同于 >=1.0.0 <2.0.0
+//   - 空格分隔的条件同时满足, || 分隔的条件满足其一, 如 ">=1.2.0 <2.0.0 || ^3.0.0"
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{}
+	for _, group := range strings.Split(s, "||") {
+		fields := strings.Fields(group)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("selector: empty version constraint %q", s)
+		}
+		var cmps []comparator
+		for _, f := range fields {
+			parsed, err := parseComparator(f)
+			if err != nil {
+				return nil, fmt.Errorf("selector: version constraint %q: %w", s, err)
+			}
+			cmps = append(cmps, parsed...)
+		}
+		c.groups = append(c.groups, cmps)
+	}
+	return c, nil
+}
+
+// Check 版本是否满足约束, 无法解析的版本不满足
+func (c *Constraint) Check(s string) bool {
+	v, err := parseVersion(s)
+	if err != nil {
+		return false
+	}
+	for _, group := range c.groups {
+		ok := true
+		for _, cmp := range group {
+			if !cmp.check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			break
+		}
+	}
+	v, err := parseVersion(strings.TrimPrefix(s, op))
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "":
+		return []comparator{{op: "=", v: v}}, nil
+	case "^":
+		upper := version{major: v.major + 1}
+		switch {
+		case v.major > 0 || v.parts == 1:
+		case v.minor > 0 || v.parts == 2:
+			upper = version{minor: v.minor + 1}
+		default:
+			upper = version{patch: v.patch + 1}
+		}
+		return []comparator{{op: ">=", v: v}, {op: "<", v: upper}}, nil
+	case "~":
+		upper := version{major: v.major, minor: v.minor + 1}
+		if v.parts == 1 {
+			upper = version{major: v.major + 1}
+		}
+		return []comparator{{op: ">=", v: v}, {op: "<", v: upper}}, nil
+	}
+	return []comparator{{op: op, v: v}}, nil
+}
+
+func (c comparator) check(v version) bool {
+	n := v.compare(c.v)
+	switch c.op {
+	case "=":
+		return n == 0
+	case "!=":
+		return n != 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	}
+	return false
+}
+
+type version struct {
+	major, minor, patch int
+	pre                 string
+	parts               int // 给出的版本号段数, 用于展开 ^、~
+}
+
+func parseVersion(s string) (version, error) {
+	s = strings.TrimPrefix(s, "v")
+	var v version
+	withoutBuild, _, _ := strings.Cut(s, "+") // 忽略构建元数据, 其中可能包含 -
+	core, pre, _ := strings.Cut(withoutBuild, "-")
+	v.pre = pre
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 || parts[0] == "" {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	v.parts = len(parts)
+	nums := []*int{&v.major, &v.minor, &v.patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// compare 先比较 MAJOR.MINOR.PATCH, 相同时预发布版本低于正式版本
+func (v version) compare(o version) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			if d > 0 {
+				return 1
+			}
+			return -1
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	}
+	return comparePrerelease(v.pre, o.pre)
+}
+
+// comparePrerelease 逐个比较 . 分隔的标识符: 都是数字时按数值比较, 数字低于非数字, 其余按字符串比较;
+// 前面的标识符都相同时, 标识符少的版本更低
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an > bn {
+					return 1
+				}
+				return -1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if n := strings.Compare(as[i], bs[i]); n != 0 {
+				return n
+			}
+		}
+	}
+	switch {
+	case len(as) > len(bs):
+		return 1
+	case len(as) < len(bs):
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package selector
+
+import (
+	"context"
+	"sync"
+)
+
+// NewWeightedRoundRobin 平滑加权轮询 (同 nginx), 权重见 Node.Weight.
+// 权重 5、1、1 的节点选择顺序为 a a b a c a a, 而不是连续选择 a.
+func NewWeightedRoundRobin() Balancer {
+	return &weightedRoundRobin{current: make(map[string]int)}
+}
+
+type weightedRoundRobin struct {
+	mu      sync.Mutex
+	current map[string]int // 地址 -> 当前权重
+}
+
+func (b *weightedRoundRobin) Pick(_ context.Context, nodes []*Node) (*Node, DoneFunc, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.current) > len(nodes) {
+		// 清理已下线节点
+		alive := make(map[string]int, len(nodes))
+		for _, n := range nodes {
+			alive[n.Address] = b.current[n.Address]
+		}
+		b.current = alive
+	}
+
+	var best *Node
+	total := 0
+	for _, n := range nodes {
+		w := n.Weight()
+		total += w
+		b.current[n.Address] += w
+		if best == nil || b.current[n.Address] > b.current[best.Address] {
+			best = n
+		}
+	}
+	b.current[best.Address] -= total
+	return best, noopDone, nil
+}
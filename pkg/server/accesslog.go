@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+)
+
+// defaultSkipPaths 健康检查、指标接口调用频繁, 默认不记录访问日志
+var defaultSkipPaths = []string{"/health", "/livez", "/readyz", "/metrics"}
+
+type AccessLogOption func(o *accessLogOptions)
+
+type accessLogOptions struct {
+	skipPaths  map[string]bool
+	sampleRate float64
+	bodyLimit  int
+}
+
+// WithSkipPaths 不记录访问日志的路径, 覆盖默认的 /health、/livez、/readyz、/metrics
+func WithSkipPaths(paths ...string) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.skipPaths = make(map[string]bool, len(paths))
+		for _, p := range paths {
+			o.skipPaths[p] = true
+		}
+	}
+}
+
+// WithSampleRate 成功请求 (status < 400) 的采样率 0~1, 默认 1 全部记录; 失败请求始终记录
+func WithSampleRate(rate float64) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.sampleRate = rate
+	}
+}
+
+// WithBody 记录请求体、响应体, 每个最多 limit 字节.
+// JSON、表单按字段名脱敏 (password、token、secret 等), 其余类型只记录长度.
+func WithBody(limit int) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.bodyLimit = limit
+	}
+}
+
+// AccessLog 通过 logger 输出结构化访问日志, request_id、trace_id 由 ctx 带出.
+// 5xx 以 error 级别输出, 其余为 info.
+func AccessLog(opts ...AccessLogOption) gin.HandlerFunc {
+	o := &accessLogOptions{sampleRate: 1}
+	WithSkipPaths(defaultSkipPaths...)(o)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if o.skipPaths[path] {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		var reqBody []byte
+		var bw *bodyWriter
+		if o.bodyLimit > 0 {
+			reqBody = peekBody(c, o.bodyLimit)
+			bw = &bodyWriter{ResponseWriter: c.Writer, limit: o.bodyLimit}
+			c.Writer = bw
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < 400 && o.sampleRate < 1 && rand.Float64() >= o.sampleRate {
+			return
+		}
+		kv := []any{
+			"method", c.Request.Method,
+			"path", path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency", time.Since(start).String(),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+			"user_agent", c.Request.UserAgent(),
+		}
+		if query := c.Request.URL.RawQuery; query != "" {
+			kv = append(kv, "query", redactForm(query))
+		}
+		if len(c.Errors) > 0 {
+			kv = append(kv, "errors", c.Errors.String())
+		}
+		if bw != nil {
+			kv = append(kv,
+				"request_body", formatBody(c.ContentType(), reqBody, o.bodyLimit),
+				"response_body", formatBody(c.Writer.Header().Get("Content-Type"), bw.buf.Bytes(), o.bodyLimit))
+		}
+
+		ctx := c.Request.Context()
+		if status >= 500 {
+			logger.Errorc(ctx, "http access", kv...)
+			return
+		}
+		logger.Infoc(ctx, "http access", kv...)
+	}
+}
+
+// peekBody 读取请求体前 limit 字节, 并还原请求体供 handler 读取
+func peekBody(c *gin.Context, limit int) []byte {
+	if c.Request.Body == nil {
+		return nil
+	}
+	buf, _ := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+	c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(buf), c.Request.Body), Closer: c.Request.Body}
+	return buf
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyWriter 写响应的同时保留前 limit+1 字节, 多出的 1 字节用于判断是否截断
+type bodyWriter struct {
+	gin.ResponseWriter
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	if remain := w.limit + 1 - w.buf.Len(); remain > 0 {
+		w.buf.Write(b[:min(len(b), remain)])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// formatBody 脱敏后的 body, 超出 limit 的部分截断
+func formatBody(contentType string, body []byte, limit int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+	var s string
+	switch {
+	case strings.Contains(contentType, "json"):
+		var v any
+		if !truncated && json.Unmarshal(body, &v) == nil {
+			b, _ := json.Marshal(redact(v))
+			return string(b)
+		}
+		// 截断的 JSON 无法解析, 不输出内容避免泄露敏感字段
+		s = "[json body]"
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		s = redactForm(string(body))
+	default:
+		s = "[" + contentType + " body]"
+	}
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
+
+// redactForm 按字段名脱敏 query、表单. 逐个字段处理, 格式错误 (如非法的 % 转义) 时同样脱敏
+func redactForm(raw string) string {
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		k, _, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		name := k
+		if unescaped, err := url.QueryUnescape(k); err == nil {
+			name = unescaped
+		}
+		if isSensitive(name) {
+			pairs[i] = k + "=" + url.QueryEscape(redacted)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
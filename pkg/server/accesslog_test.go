@@ -0,0 +1,104 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/requestid"
+	"github.com/gogoclouds/project-layout/pkg/server"
+)
+
+// captureLogger 记录访问日志的字段
+type captureLogger struct {
+	logger.ILogger
+	mu      sync.Mutex
+	entries []map[string]any
+}
+
+func (l *captureLogger) Infoc(ctx context.Context, msg string, kv ...any) {
+	l.record(ctx, "info", kv)
+}
+
+func (l *captureLogger) Errorc(ctx context.Context, msg string, kv ...any) {
+	l.record(ctx, "error", kv)
+}
+
+func (l *captureLogger) record(ctx context.Context, level string, kv []any) {
+	kv = append(logger.Fields(ctx), kv...)
+	entry := map[string]any{"level": level}
+	for i := 0; i+1 < len(kv); i += 2 {
+		entry[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	l.mu.Lock()
+	l.entries = append(l.entries, entry)
+	l.mu.Unlock()
+}
+
+func Test_AccessLog(t *testing.T) {
+	capture := &captureLogger{}
+	logger.SetLogger(capture)
+	defer logger.InitZapLogger(logger.NewConfig(logger.WithFilepath("./logs")))
+
+	e := gin.New()
+	e.Use(requestid.GinMiddleware(), server.AccessLog(server.WithBody(64)))
+	e.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	e.POST("/users/:id", func(c *gin.Context) {
+		var body map[string]any
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusInternalServerError, gin.H{"name": body["name"], "token": "t-1"})
+	})
+
+	do := func(method, target, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(requestid.Header, "req-1")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	do(http.MethodGet, "/health", "")
+	do(http.MethodPost, "/users/1?name=%zz&password=p", `{"name":"n","password":"p"}`)
+
+	if len(capture.entries) != 1 {
+		t.Fatalf("entries = %d, want 1 (skip /health)", len(capture.entries))
+	}
+	entry := capture.entries[0]
+	want := map[string]any{
+		"level":         "error",
+		"request_id":    "req-1",
+		"path":          "/users/1",
+		"route":         "/users/:id",
+		"status":        http.StatusInternalServerError,
+		"query":         "name=%zz&password=%2A%2A%2A%2A%2A%2A", // 格式错误的 query 同样脱敏
+		"request_body":  `{"name":"n","password":"******"}`,
+		"response_body": `{"name":"n","token":"******"}`,
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+func Test_AccessLogSample(t *testing.T) {
+	capture := &captureLogger{}
+	logger.SetLogger(capture)
+	defer logger.InitZapLogger(logger.NewConfig(logger.WithFilepath("./logs")))
+
+	e := gin.New()
+	e.Use(server.AccessLog(server.WithSampleRate(0)))
+	e.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	e.GET("/bad", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	for _, path := range []string{"/ok", "/bad"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// 失败请求不参与采样
+	if len(capture.entries) != 1 || capture.entries[0]["path"] != "/bad" {
+		t.Fatalf("entries = %v, want only /bad", capture.entries)
+	}
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+)
+
+var (
+	_ Server   = (*AdminServer)(nil)
+	_ Listener = (*AdminServer)(nil)
+)
+
+// sensitiveKeys 配置中包含这些词的字段值会被脱敏
+var sensitiveKeys = []string{"password", "secret", "token", "key", "source", "dsn"}
+
+const redacted = "******"
+
+// AdminServer 管理/调试 http 服务, 应只监听内网地址.
+//   - /debug/pprof/* net/http/pprof
+//   - /debug/stats 运行时统计 (goroutine、内存、GC)
+//   - /debug/build 构建信息
+//   - /debug/config 已加载的配置, 敏感字段脱敏
+//   - /debug/instance 注册到注册中心的实例
+//   - /loglevel GET 查询、PUT {"level":"debug"} 修改日志级别
+//   - /metrics Prometheus 指标
+//
+// 不实现 Endpointer, 不会注册到注册中心.
+type AdminServer struct {
+	*http.Server
+
+	mux       *http.ServeMux
+	lis       net.Listener
+	startTime time.Time
+	conf      any
+	instances func() any
+}
+
+type AdminOption func(s *AdminServer)
+
+// WithAdminConfig /debug/config 返回的配置
+func WithAdminConfig(conf any) AdminOption {
+	return func(s *AdminServer) {
+		s.conf = conf
+	}
+}
+
+// WithAdminInstances /debug/instance 返回的实例信息
+func WithAdminInstances(fn func() any) AdminOption {
+	return func(s *AdminServer) {
+		s.instances = fn
+	}
+}
+
+func NewAdminServer(addr string, opts ...AdminOption) *AdminServer {
+	mux := http.NewServeMux()
+	srv := &AdminServer{
+		Server:    &http.Server{Addr: addr, Handler: mux},
+		mux:       mux,
+		startTime: time.Now(),
+	}
+	for _, o := range opts {
+		o(srv)
+	}
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/stats", srv.stats)
+	mux.HandleFunc("/debug/build", srv.build)
+	mux.HandleFunc("/debug/config", srv.config)
+	mux.HandleFunc("/debug/instance", srv.instance)
+	mux.Handle("/loglevel", logger.LevelHandler())
+	mux.Handle("/metrics", metrics.Handler())
+	return srv
+}
+
+// Handle 追加自定义调试接口
+func (s *AdminServer) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Listen 绑定监听地址, 重复调用只绑定一次
+func (s *AdminServer) Listen() error {
+	if s.lis != nil {
+		return nil
+	}
+	lis, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return &ListenError{Kind: KindAdmin, Addr: s.Addr, Err: err}
+	}
+	s.lis = lis
+	return nil
+}
+
+// ListenAddr 真实监听地址, 未绑定时返回 nil
+func (s *AdminServer) ListenAddr() net.Addr {
+	if s.lis == nil {
+		return nil
+	}
+	return s.lis.Addr()
+}
+
+// Start 启动管理服务, 阻塞直到服务退出
+func (s *AdminServer) Start(_ context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	logger.Infof("admin server listening on: %s", s.lis.Addr().String())
+	if err := s.Serve(s.lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop 停止管理服务, ctx 超时后强制关闭所有连接 (如进行中的 pprof profile)
+func (s *AdminServer) Stop(ctx context.Context) error {
+	if s.lis != nil {
+		defer s.lis.Close()
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		_ = s.Close()
+		return err
+	}
+	return nil
+}
+
+func (s *AdminServer) stats(w http.ResponseWriter, _ *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"uptime":     time.Since(s.startTime).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"cpus":       runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"heap": map[string]any{
+			"alloc":    m.HeapAlloc,
+			"inuse":    m.HeapInuse,
+			"idle":     m.HeapIdle,
+			"released": m.HeapReleased,
+			"objects":  m.HeapObjects,
+			"sys":      m.HeapSys,
+		},
+		"sys": m.Sys,
+		"gc": map[string]any{
+			"num":         m.NumGC,
+			"forced":      m.NumForcedGC,
+			"pauseTotal":  time.Duration(m.PauseTotalNs).String(),
+			"lastPause":   time.Duration(m.PauseNs[(m.NumGC+255)%256]).String(),
+			"lastGC":      time.Unix(0, int64(m.LastGC)),
+			"nextGC":      m.NextGC,
+			"cpuFraction": m.GCCPUFraction,
+		},
+	})
+}
+
+func (s *AdminServer) build(w http.ResponseWriter, _ *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "build info not available"})
+		return
+	}
+	settings := make(map[string]string, len(info.Settings))
+	for _, st := range info.Settings {
+		settings[st.Key] = st.Value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"goVersion": info.GoVersion,
+		"path":      info.Path,
+		"version":   info.Main.Version,
+		"settings":  settings,
+	})
+}
+
+func (s *AdminServer) config(w http.ResponseWriter, _ *http.Request) {
+	if s.conf == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "config not available"})
+		return
+	}
+	b, err := json.Marshal(s.conf)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	var v any
+	_ = json.Unmarshal(b, &v)
+	writeJSON(w, http.StatusOK, redact(v))
+}
+
+func (s *AdminServer) instance(w http.ResponseWriter, _ *http.Request) {
+	if s.instances == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "instance not available"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.instances())
+}
+
+// redact 按字段名脱敏, 非空的字符串值替换为 ******
+func redact(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			if str, ok := item.(string); ok && str != "" && isSensitive(k) {
+				val[k] = redacted
+				continue
+			}
+			val[k] = redact(item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = redact(item)
+		}
+	}
+	return v
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,62 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/server"
+)
+
+func Test_AdminServer(t *testing.T) {
+	conf := map[string]any{
+		"name":  "admin-test",
+		"redis": map[string]any{"addrs": []string{"127.0.0.1:6379"}, "password": "p@ss"},
+		"db":    map[string]any{"source": "root:root@tcp(127.0.0.1:3306)/gogo"},
+	}
+	srv := server.NewAdminServer("127.0.0.1:0", server.WithAdminConfig(conf))
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Start(context.Background())
+	defer srv.Stop(context.Background())
+	base := "http://" + srv.ListenAddr().String()
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %s %s", path, resp.Status, body)
+		}
+		return string(body)
+	}
+	get("/debug/pprof/")
+	get("/debug/stats")
+	get("/debug/build")
+	if body := get("/debug/config"); strings.Contains(body, "p@ss") || strings.Contains(body, "root:root") ||
+		!strings.Contains(body, "admin-test") {
+		t.Fatalf("config not redacted: %s", body)
+	}
+
+	defer logger.SetLevel(logger.Level())
+	req, _ := http.NewRequest(http.MethodPut, base+"/loglevel", strings.NewReader(`{"level":"error"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || logger.Level() != logger.LogLevel_Error {
+		t.Fatalf("log level not updated: %s %s", resp.Status, logger.Level())
+	}
+	if body := get("/loglevel"); !strings.Contains(body, "error") {
+		t.Fatalf("unexpected log level: %s", body)
+	}
+}
@@ -4,55 +4,188 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/gogoclouds/gogo/g"
 	"github.com/gogoclouds/gogo/web/gin/middleware"
 	"github.com/gogoclouds/gogo/web/r"
+	"github.com/gogoclouds/project-layout/pkg/health"
+	"github.com/gogoclouds/project-layout/pkg/host"
 	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+	"github.com/gogoclouds/project-layout/pkg/network"
+	"github.com/gogoclouds/project-layout/pkg/ratelimit"
+	"github.com/gogoclouds/project-layout/pkg/requestid"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
+	"net"
 	"net/http"
-	"sync"
-	"time"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RunHttpServer(exit <-chan struct{}, wg *sync.WaitGroup, addr string, register func(e *gin.Engine)) {
-	wg.Add(1)
-	defer wg.Done()
+var (
+	_ Server     = (*HttpServer)(nil)
+	_ Listener   = (*HttpServer)(nil)
+	_ Endpointer = (*HttpServer)(nil)
+)
+
+// HttpServer 基于 Gin 的 http 传输服务
+type HttpServer struct {
+	*http.Server
+
+	engine *gin.Engine
+	lis    net.Listener
+
+	name, version string
+	health        *health.Checker
+	accessLog     []AccessLogOption
+	rateLimit     *ratelimit.Limiters
+	metricsRoute  bool
+}
+
+type HttpOption func(s *HttpServer)
+
+// WithServiceInfo 健康检查接口返回的服务名和版本号
+func WithServiceInfo(name, version string) HttpOption {
+	return func(s *HttpServer) {
+		s.name, s.version = name, version
+	}
+}
+
+// WithHealth 就绪检查使用的 Checker, 不设置时服务启动即就绪
+func WithHealth(h *health.Checker) HttpOption {
+	return func(s *HttpServer) {
+		s.health = h
+	}
+}
+
+// WithAccessLog 访问日志选项, 见 AccessLog
+func WithAccessLog(opts ...AccessLogOption) HttpOption {
+	return func(s *HttpServer) {
+		s.accessLog = opts
+	}
+}
+
+// WithRateLimit 按路由限流, 见 ratelimit.GinMiddleware
+func WithRateLimit(l *ratelimit.Limiters) HttpOption {
+	return func(s *HttpServer) {
+		s.rateLimit = l
+	}
+}
+
+// WithMetricsRoute 在业务端口注册 /metrics. 默认只由管理服务提供, 避免指标暴露在公网端口
+func WithMetricsRoute() HttpOption {
+	return func(s *HttpServer) {
+		s.metricsRoute = true
+	}
+}
+
+func NewHttpServer(addr string, register func(e *gin.Engine), opts ...HttpOption) *HttpServer {
 	e := gin.New()
-	e.Use(gin.Logger()) // TODO -> zap.Logger
+	srv := &HttpServer{
+		Server: &http.Server{Addr: addr, Handler: e},
+		engine: e,
+		name:   "http-server",
+	}
+	for _, o := range opts {
+		o(srv)
+	}
+
+	e.Use(tracing.GinMiddleware(srv.name)) // 最先执行, 后续中间件及日志可以取到链路信息
+	e.Use(requestid.GinMiddleware())
+	e.Use(AccessLog(srv.accessLog...))
+	e.Use(metrics.GinMiddleware()) // 在 Recovery 之前, 记录 panic 恢复后的状态码
+	if srv.rateLimit != nil {
+		e.Use(ratelimit.GinMiddleware(srv.rateLimit))
+	}
 	e.Use(middleware.Recovery())
 	e.Use(middleware.LoggerResponseFail())
 
-	healthApi(e) // provide health API
-	register(e)  // register router
+	if srv.health == nil {
+		srv.health = health.New()
+		srv.health.SetReady(true)
+	}
+
+	srv.healthApi(e) // provide health API
+	if srv.metricsRoute {
+		e.GET("/metrics", gin.WrapH(metrics.Handler())) // provide metrics API
+	}
+	register(e) // register router
+	return srv
+}
 
-	srv := &http.Server{Addr: addr, Handler: e}
+// Engine 返回 gin 引擎, 可用于追加中间件或路由
+func (s *HttpServer) Engine() *gin.Engine {
+	return s.engine
+}
 
-	// Initializing the server in a goroutine so that
-	// it won't block the graceful shutdown handling below
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Panicf("http listen: %s\n", err)
-		}
-	}()
+// Listen 绑定监听地址, 重复调用只绑定一次
+func (s *HttpServer) Listen() error {
+	if s.lis != nil {
+		return nil
+	}
+	lis, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return &ListenError{Kind: KindHTTP, Addr: s.Addr, Err: err}
+	}
+	s.lis = lis
+	return nil
+}
 
-	<-exit
+// Endpoint 真实监听地址, 未绑定时先绑定
+func (s *HttpServer) Endpoint() (*url.URL, error) {
+	if err := s.Listen(); err != nil {
+		return nil, err
+	}
+	addr, err := host.Extract(s.Addr, s.lis)
+	if err != nil {
+		return nil, err
+	}
+	return network.NewEndpoint(KindHTTP, addr), nil
+}
+
+// Start 启动 http 服务, 阻塞直到服务退出
+func (s *HttpServer) Start(_ context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	logger.Infof("http server listening on: %s", s.lis.Addr().String())
+	if err := s.Serve(s.lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop 优雅停止 http 服务, ctx 超时后强制关闭所有连接
+func (s *HttpServer) Stop(ctx context.Context) error {
 	logger.Info("Shutting down http server...")
-	// The context is used to inform the server it has 5 seconds to finish
-	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Errorf("Http server forced to shutdown: %w", err)
+	if s.lis != nil {
+		// 已绑定但尚未 Serve 的监听不受 Shutdown 管理, 需要手动关闭
+		defer s.lis.Close()
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		logger.Errorf("Http server forced to shutdown: %v", err)
+		_ = s.Close()
+		return err
 	}
 	logger.Info("http server exiting")
+	return nil
 }
 
 // healthApi http check-up API
-func healthApi(e *gin.Engine) {
-	e.GET("/health", func(c *gin.Context) {
-		appConf := g.Conf.App()
-		msg := fmt.Sprintf("%s %s, is active", appConf.Name, appConf.Version)
+//   - /livez、/health 存活检查, 进程能响应即返回 200
+//   - /readyz 就绪检查, 未就绪或任一检查项失败返回 503 及各检查项详情
+func (s *HttpServer) healthApi(e *gin.Engine) {
+	livez := func(c *gin.Context) {
+		msg := fmt.Sprintf("%s %s, is active", s.name, s.version)
 		c.JSON(http.StatusOK, r.SuccessMsg(msg))
+	}
+	e.GET("/health", livez)
+	e.GET("/livez", livez)
+	e.GET("/readyz", func(c *gin.Context) {
+		report := s.health.Check(c.Request.Context())
+		if report.Status != health.StatusUp {
+			c.JSON(http.StatusServiceUnavailable, r.FailMsgDetails("not ready", report))
+			return
+		}
+		c.JSON(http.StatusOK, r.SuccessData(report))
 	})
 }
@@ -1,22 +1,33 @@
 package server_test
 
 import (
+	"context"
+	"errors"
+	"github.com/gogoclouds/project-layout/pkg/logger"
 	"github.com/gogoclouds/project-layout/pkg/server"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Test_HttpServer(t *testing.T) {
-	exitHttp := make(chan struct{})
-	doneExitHttp := make(chan struct{})
-	server.RunHttpServer(exitHttp, doneExitHttp, ":8080", router)
+func init() {
+	logger.InitZapLogger(logger.NewConfig(logger.WithFilepath("./logs")))
 }
 
-func Test_HttpApi(t *testing.T) {
-	r, err := http.DefaultClient.Get("http://127.0.0.1:8080/ping")
+func Test_HttpServer(t *testing.T) {
+	srv := server.NewHttpServer("127.0.0.1:18080", router)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start(context.Background())
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	r, err := http.DefaultClient.Get("http://127.0.0.1:18080/ping")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -29,6 +40,15 @@ func Test_HttpApi(t *testing.T) {
 		t.Fatal(err2)
 	}
 	t.Logf("%s", b)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = srv.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-errCh; err != nil {
+		t.Fatal(err)
+	}
 }
 
 func router(e *gin.Engine) {
@@ -39,3 +59,37 @@ func router(e *gin.Engine) {
 		})
 	})
 }
+
+func Test_HttpServerListenError(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	srv := server.NewHttpServer(lis.Addr().String(), router)
+	err = srv.Start(context.Background())
+	var listenErr *server.ListenError
+	if !errors.As(err, &listenErr) || listenErr.Kind != server.KindHTTP {
+		t.Fatalf("expected http listen error, got: %v", err)
+	}
+}
+
+func Test_HttpServerMetricsRoute(t *testing.T) {
+	status := func(srv *server.HttpServer) int {
+		rec := httptest.NewRecorder()
+		srv.Engine().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec.Code
+	}
+	// 默认不在业务端口暴露指标, 业务可以注册自己的 /metrics
+	srv := server.NewHttpServer("127.0.0.1:0", func(e *gin.Engine) {
+		e.GET("/metrics", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	})
+	if code := status(srv); code != http.StatusNoContent {
+		t.Fatalf("status = %d, want business /metrics", code)
+	}
+	srv = server.NewHttpServer("127.0.0.1:0", router, server.WithMetricsRoute())
+	if code := status(srv); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 with WithMetricsRoute", code)
+	}
+}
@@ -1,55 +1,53 @@
 package server
 
 import (
-	"github.com/gogoclouds/project-layout/pkg/logger"
+	"context"
+	"sync/atomic"
+
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	clientgrpc "github.com/gogoclouds/project-layout/pkg/client/grpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/health"
-	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
-	"net"
-	"sync"
 )
 
-func RunRpcServer(exit <-chan struct{}, wg *sync.WaitGroup, addr string, register func(server *grpc.Server)) {
-	wg.Add(1)
-	defer wg.Done()
-	lis, err := net.Listen("tcp", addr)
-	if err != nil {
-		panic(err)
-	}
-	s := grpc.NewServer()
-
-	// 注册健康检查服务
-	healthgrpc.RegisterHealthServer(s, health.NewServer())
-	register(s)
-
-	go func() {
-		if err = s.Serve(lis); err != nil {
-			logger.Panicf("http listen: %s\n", err)
-		}
-	}()
-	<-exit
-	s.GracefulStop() // 优雅停止
-}
-
 // RPC Dial
 
-var rpcClientMap = make(map[string]*grpc.ClientConn)
+var (
+	// rpcBreaker RpcDial 连接使用的熔断, 每次调用时读取, 设置后不需要重建连接
+	rpcBreaker atomic.Pointer[breaker.Group]
+	rpcClients = clientgrpc.NewPool(clientgrpc.WithDialOptions(
+		grpc.WithChainUnaryInterceptor(rpcBreakerUnary),
+		grpc.WithChainStreamInterceptor(rpcBreakerStream)))
+)
 
+// RpcDial 获取 serverName 的连接, 连接按 serverName 缓存, 并发安全.
+// 设置 UseRpcBreaker 后按 目标服务 + 方法 熔断.
+//
+// Deprecated: 使用 client/grpc 包的 Pool, 支持 discovery:///<service> 服务发现及负载均衡.
 func RpcDial(serverName string) (*grpc.ClientConn, error) {
-	if cc, ok := rpcClientMap[serverName]; ok {
-		state := cc.GetState()
-		if state == connectivity.Ready {
-			return cc, nil
-		}
+	return rpcClients.Get(context.Background(), serverName)
+}
+
+// UseRpcBreaker RpcDial 的连接使用 g 熔断, App 按配置 breaker 自动设置, 返回的 release 取消设置.
+// 进程内只有一个 RpcDial 连接池, 已设置其他熔断时不替换, 多个 App 时先初始化的生效.
+func UseRpcBreaker(g *breaker.Group) (release func()) {
+	if g == nil || !rpcBreaker.CompareAndSwap(nil, g) {
+		return func() {}
 	}
+	return func() { rpcBreaker.CompareAndSwap(g, nil) }
+}
+
+func rpcBreakerUnary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if g := rpcBreaker.Load(); g != nil {
+		return breaker.UnaryClientInterceptor(g)(ctx, method, req, reply, cc, invoker, opts...)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
 
-	// conn, err := grpc.Dial(serverName, grpc.WithInsecure())
-	conn, err := grpc.Dial(serverName, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, err
+func rpcBreakerStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+	streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if g := rpcBreaker.Load(); g != nil {
+		return breaker.StreamClientInterceptor(g)(ctx, desc, cc, method, streamer, opts...)
 	}
-	rpcClientMap[serverName] = conn
-	return conn, nil
+	return streamer(ctx, desc, cc, method, opts...)
 }
@@ -1,9 +1,19 @@
 package rpc
 
 import (
+	"context"
+	"errors"
+	apphealth "github.com/gogoclouds/project-layout/pkg/health"
 	"github.com/gogoclouds/project-layout/pkg/host"
+	"github.com/gogoclouds/project-layout/pkg/logger"
 	apimd "github.com/gogoclouds/project-layout/pkg/metadata"
+	"github.com/gogoclouds/project-layout/pkg/metrics"
+	"github.com/gogoclouds/project-layout/pkg/network"
+	"github.com/gogoclouds/project-layout/pkg/ratelimit"
+	"github.com/gogoclouds/project-layout/pkg/requestid"
+	"github.com/gogoclouds/project-layout/pkg/server"
 	"github.com/gogoclouds/project-layout/pkg/server/rpc/serverinterceptors"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
@@ -13,6 +23,12 @@ import (
 	"time"
 )
 
+var (
+	_ server.Server     = (*Server)(nil)
+	_ server.Listener   = (*Server)(nil)
+	_ server.Endpointer = (*Server)(nil)
+)
+
 type ServerOption func(s *Server)
 
 type Server struct {
@@ -23,10 +39,13 @@ type Server struct {
 	streamInterceptors []grpc.StreamServerInterceptor
 	grpcOptions        []grpc.ServerOption
 
-	timeout  time.Duration
-	listen   net.Listener
-	health   *health.Server
-	endpoint *url.URL
+	timeout   time.Duration
+	accessLog []serverinterceptors.LogOption
+	rateLimit *ratelimit.Limiters
+	listen    net.Listener
+	health    *health.Server
+	checker   *apphealth.Checker
+	endpoint  *url.URL
 }
 
 func WithAddress(address string) ServerOption {
@@ -35,6 +54,35 @@ func WithAddress(address string) ServerOption {
 	}
 }
 
+// WithTimeout 单次请求的处理超时时间, <= 0 不限制
+func WithTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.timeout = timeout
+	}
+}
+
+// WithAccessLog 访问日志选项, 见 serverinterceptors.UnaryLoggerInterceptor
+func WithAccessLog(opts ...serverinterceptors.LogOption) ServerOption {
+	return func(s *Server) {
+		s.accessLog = opts
+	}
+}
+
+// WithRateLimit 按方法限流, 见 ratelimit.UnaryServerInterceptor
+func WithRateLimit(l *ratelimit.Limiters) ServerOption {
+	return func(s *Server) {
+		s.rateLimit = l
+	}
+}
+
+// WithHealth 由 Checker 的就绪状态驱动 grpc 健康检查服务状态, 未就绪时为 NOT_SERVING.
+// 不设置时服务启动即为 SERVING.
+func WithHealth(c *apphealth.Checker) ServerOption {
+	return func(s *Server) {
+		s.checker = c
+	}
+}
+
 func NewServer(opts ...ServerOption) *Server {
 	srv := &Server{
 		address: ":0",
@@ -44,7 +92,12 @@ func NewServer(opts ...ServerOption) *Server {
 	for _, o := range opts {
 		o(srv)
 	}
+	// metrics、访问日志在 recover、timeout 之外, 记录 panic 恢复、超时后的最终状态码
 	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		requestid.UnaryServerInterceptor(),
+		metrics.UnaryServerInterceptor(),
+		serverinterceptors.UnaryLoggerInterceptor(srv.accessLog...),
+		ratelimit.UnaryServerInterceptor(srv.rateLimit), // 被拒绝的请求同样记录访问日志、指标
 		serverinterceptors.UnaryRecoverInterceptor,
 	}
 	if srv.timeout > 0 {
@@ -53,23 +106,98 @@ func NewServer(opts ...ServerOption) *Server {
 	if len(srv.unaryInterceptors) > 0 {
 		unaryInterceptors = append(unaryInterceptors, srv.unaryInterceptors...)
 	}
-	grpcOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(unaryInterceptors...)}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		requestid.StreamServerInterceptor(),
+		metrics.StreamServerInterceptor(),
+		serverinterceptors.StreamLoggerInterceptor(srv.accessLog...),
+		ratelimit.StreamServerInterceptor(srv.rateLimit),
+		serverinterceptors.StreamRecoverInterceptor,
+	}
+	if len(srv.streamInterceptors) > 0 {
+		streamInterceptors = append(streamInterceptors, srv.streamInterceptors...)
+	}
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		tracing.ServerOption(),
+	}
 	if len(srv.grpcOptions) > 0 {
 		grpcOpts = append(grpcOpts, srv.grpcOptions...)
 	}
 	srv.Server = grpc.NewServer(grpcOpts...)
-	// 解析address
-	if err := srv.listenAndEndpoint(); err != nil {
-		return nil
-	}
 	// 注册 health
 	grpc_health_v1.RegisterHealthServer(srv.Server, srv.health)
+	if srv.checker != nil {
+		srv.checker.OnChange(func(ready bool) {
+			status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+			if ready {
+				status = grpc_health_v1.HealthCheckResponse_SERVING
+			}
+			srv.health.SetServingStatus("", status)
+		})
+	}
 	// 可以支持用户通过grpc的一个接口查看当前支持的所有rpc服务
 	apimd.RegisterMetadataServer(srv.Server, apimd.NewServer(srv.Server))
 	reflection.Register(srv.Server)
 	return srv
 }
 
+// Listen 绑定监听地址并解析 endpoint, 重复调用只绑定一次
+func (s *Server) Listen() error {
+	// 解析address
+	if err := s.listenAndEndpoint(); err != nil {
+		return &server.ListenError{Kind: server.KindGRPC, Addr: s.address, Err: err}
+	}
+	return nil
+}
+
+// Endpoint 真实监听地址, 未绑定时先绑定
+func (s *Server) Endpoint() (*url.URL, error) {
+	if err := s.Listen(); err != nil {
+		return nil, err
+	}
+	return s.endpoint, nil
+}
+
+// Start 监听地址并启动 rpc 服务, 阻塞直到服务退出
+func (s *Server) Start(_ context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	logger.Infof("rpc server listening on: %s", s.listen.Addr().String())
+	if err := s.Serve(s.listen); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Stop 优雅停止 rpc 服务, ctx 超时后强制关闭所有连接
+func (s *Server) Stop(ctx context.Context) error {
+	logger.Info("Shutting down rpc server...")
+	s.health.Shutdown()
+	defer func() {
+		if s.listen != nil {
+			// 已绑定但尚未 Serve 的监听不受 GracefulStop 管理, 需要手动关闭
+			_ = s.listen.Close()
+		}
+	}()
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop() // 优雅停止
+		close(done)
+	}()
+	select {
+	case <-done:
+		logger.Info("rpc server exiting")
+		return nil
+	case <-ctx.Done():
+		logger.Errorf("Rpc server forced to stop: %v", ctx.Err())
+		s.Server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
 func (s *Server) listenAndEndpoint() error {
 	if s.listen == nil {
 		liste, err := net.Listen("tcp", s.address)
@@ -81,8 +209,9 @@ func (s *Server) listenAndEndpoint() error {
 	addr, err := host.Extract(s.address, s.listen)
 	if err != nil {
 		_ = s.listen.Close()
+		s.listen = nil
 		return err
 	}
-	s.endpoint = &url.URL{Scheme: "grpc", Host: addr}
+	s.endpoint = network.NewEndpoint(server.KindGRPC, addr)
 	return nil
 }
@@ -0,0 +1,271 @@
+package serverinterceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const redacted = "******"
+
+// defaultMethodLevels health checks are called frequently, only logged at debug level by default.
+var defaultMethodLevels = map[string]logger.LogLevel{
+	"/grpc.health.v1.Health/Check": logger.LogLevel_Debug,
+	"/grpc.health.v1.Health/Watch": logger.LogLevel_Debug,
+}
+
+var levelRanks = map[logger.LogLevel]int{
+	logger.LogLevel_Debug: 0,
+	logger.LogLevel_Info:  1,
+	logger.LogLevel_Warn:  2,
+	logger.LogLevel_Error: 3,
+}
+
+type (
+	// LogOption customizes the logging interceptors.
+	LogOption func(o *logOptions)
+
+	logOptions struct {
+		methodLevels map[string]logger.LogLevel
+		slowWarn     time.Duration
+		slowError    time.Duration
+		payload      bool
+		payloadLimit int
+		redactPaths  map[string]bool
+	}
+)
+
+// WithMethodLevel sets the level of successful calls to fullMethod, default is info.
+func WithMethodLevel(fullMethod string, level logger.LogLevel) LogOption {
+	return func(o *logOptions) {
+		o.methodLevels[fullMethod] = level
+	}
+}
+
+// WithSlowThreshold escalates calls slower than warnAfter to warn level and slower than errorAfter to error level,
+// <= 0 disables the threshold.
+func WithSlowThreshold(warnAfter, errorAfter time.Duration) LogOption {
+	return func(o *logOptions) {
+		o.slowWarn, o.slowError = warnAfter, errorAfter
+	}
+}
+
+// WithPayload logs unary requests and responses as protojson, truncated to limit bytes, <= 0 is unlimited.
+// Fields marked with [debug_redact = true] or configured by WithRedactFields are redacted.
+func WithPayload(limit int) LogOption {
+	return func(o *logOptions) {
+		o.payload, o.payloadLimit = true, limit
+	}
+}
+
+// WithRedactFields redacts payload fields by path of proto field names from the message root,
+// e.g. password, user.password.
+func WithRedactFields(paths ...string) LogOption {
+	return func(o *logOptions) {
+		for _, p := range paths {
+			o.redactPaths[p] = true
+		}
+	}
+}
+
+// UnaryLoggerInterceptor returns a func that logs unary requests,
+// request_id and grpc_method are taken from ctx, see logger.Fields.
+func UnaryLoggerInterceptor(opts ...LogOption) grpc.UnaryServerInterceptor {
+	o := buildLogOptions(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+		kv := o.fields(ctx, err, duration)
+		if o.payload {
+			kv = append(kv, "request", o.marshal(req))
+			if err == nil {
+				kv = append(kv, "response", o.marshal(resp))
+			}
+		}
+		o.log(ctx, info.FullMethod, err, duration, kv)
+		return resp, err
+	}
+}
+
+// StreamLoggerInterceptor returns a func that logs stream requests with the count of messages,
+// payloads are not logged.
+func StreamLoggerInterceptor(opts ...LogOption) grpc.StreamServerInterceptor {
+	o := buildLogOptions(opts)
+	return func(svr any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		start := time.Now()
+		stream := &countingStream{ServerStream: ss}
+		err := handler(svr, stream)
+		duration := time.Since(start)
+		ctx := ss.Context()
+		kv := append(o.fields(ctx, err, duration), "sent", stream.sent, "received", stream.received)
+		o.log(ctx, info.FullMethod, err, duration, kv)
+		return err
+	}
+}
+
+func buildLogOptions(opts []LogOption) *logOptions {
+	o := &logOptions{
+		methodLevels: make(map[string]logger.LogLevel, len(defaultMethodLevels)),
+		redactPaths:  make(map[string]bool),
+	}
+	for m, l := range defaultMethodLevels {
+		o.methodLevels[m] = l
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *logOptions) fields(ctx context.Context, err error, duration time.Duration) []any {
+	kv := []any{
+		"code", status.Code(err).String(),
+		"duration", duration.String(),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		kv = append(kv, "peer", p.Addr.String())
+	}
+	if err != nil {
+		kv = append(kv, "error", status.Convert(err).Message())
+	}
+	return kv
+}
+
+// level of the call: server errors are error, client errors are warn,
+// otherwise the method level escalated by slow thresholds.
+func (o *logOptions) level(method string, err error, duration time.Duration) logger.LogLevel {
+	level, ok := o.methodLevels[method]
+	if !ok {
+		level = logger.LogLevel_Info
+	}
+	escalate := func(l logger.LogLevel) {
+		if levelRanks[l] > levelRanks[level] {
+			level = l
+		}
+	}
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal,
+		codes.Unavailable, codes.DataLoss:
+		escalate(logger.LogLevel_Error)
+	default:
+		escalate(logger.LogLevel_Warn)
+	}
+	if o.slowError > 0 && duration >= o.slowError {
+		escalate(logger.LogLevel_Error)
+	} else if o.slowWarn > 0 && duration >= o.slowWarn {
+		escalate(logger.LogLevel_Warn)
+	}
+	return level
+}
+
+func (o *logOptions) log(ctx context.Context, method string, err error, duration time.Duration, kv []any) {
+	const msg = "grpc access"
+	switch o.level(method, err, duration) {
+	case logger.LogLevel_Debug:
+		logger.Debugc(ctx, msg, kv...)
+	case logger.LogLevel_Warn:
+		logger.Warnc(ctx, msg, kv...)
+	case logger.LogLevel_Error:
+		logger.Errorc(ctx, msg, kv...)
+	default:
+		logger.Infoc(ctx, msg, kv...)
+	}
+}
+
+// marshal converts the message to redacted protojson.
+func (o *logOptions) marshal(v any) string {
+	m, ok := v.(proto.Message)
+	if !ok || m == nil {
+		return ""
+	}
+	m = proto.Clone(m)
+	o.redact(m.ProtoReflect(), "")
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return "[" + err.Error() + "]"
+	}
+	s := string(b)
+	if o.payloadLimit > 0 && len(s) > o.payloadLimit {
+		s = s[:o.payloadLimit] + "...(truncated)"
+	}
+	return s
+}
+
+func (o *logOptions) redact(m protoreflect.Message, prefix string) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		path := string(fd.Name())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		if o.sensitive(fd, path) {
+			if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(redacted))
+			} else {
+				m.Clear(fd)
+			}
+			return true
+		}
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					o.redact(mv.Message(), path)
+					return true
+				})
+			}
+		case fd.IsList():
+			if fd.Message() != nil {
+				list := v.List()
+				for i := 0; i < list.Len(); i++ {
+					o.redact(list.Get(i).Message(), path)
+				}
+			}
+		case fd.Message() != nil:
+			o.redact(v.Message(), path)
+		}
+		return true
+	})
+}
+
+func (o *logOptions) sensitive(fd protoreflect.FieldDescriptor, path string) bool {
+	if o.redactPaths[path] {
+		return true
+	}
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDebugRedact()
+}
+
+// countingStream counts the messages sent and received.
+type countingStream struct {
+	grpc.ServerStream
+	sent, received int
+}
+
+func (s *countingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+	return err
+}
+
+func (s *countingStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package serverinterceptors_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/server/rpc/serverinterceptors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type captureLogger struct {
+	logger.ILogger
+	entries []map[string]any
+}
+
+func (l *captureLogger) Debugc(_ context.Context, _ string, kv ...any) { l.record("debug", kv) }
+func (l *captureLogger) Infoc(_ context.Context, _ string, kv ...any)  { l.record("info", kv) }
+func (l *captureLogger) Warnc(_ context.Context, _ string, kv ...any)  { l.record("warn", kv) }
+func (l *captureLogger) Errorc(_ context.Context, _ string, kv ...any) { l.record("error", kv) }
+
+func (l *captureLogger) record(level string, kv []any) {
+	entry := map[string]any{"level": level}
+	for i := 0; i+1 < len(kv); i += 2 {
+		entry[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	l.entries = append(l.entries, entry)
+}
+
+// loginDescriptor message Login { string name = 1; string password = 2 [debug_redact = true]; Login inner = 3; }
+func loginDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("login.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Login"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), Number: proto.Int32(1), JsonName: proto.String("name"),
+					Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("password"), Number: proto.Int32(2), JsonName: proto.String("password"),
+					Type:    descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Options: &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}},
+				{Name: proto.String("inner"), Number: proto.Int32(3), JsonName: proto.String("inner"),
+					Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".test.Login")},
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func TestUnaryLoggerInterceptor(t *testing.T) {
+	capture := &captureLogger{}
+	logger.SetLogger(capture)
+
+	md := loginDescriptor(t)
+	req := dynamicpb.NewMessage(md)
+	req.Set(md.Fields().ByName("name"), protoreflect.ValueOfString("n"))
+	req.Set(md.Fields().ByName("password"), protoreflect.ValueOfString("p"))
+	inner := dynamicpb.NewMessage(md)
+	inner.Set(md.Fields().ByName("name"), protoreflect.ValueOfString("secret-name"))
+	req.Set(md.Fields().ByName("inner"), protoreflect.ValueOfMessage(inner))
+
+	interceptor := serverinterceptors.UnaryLoggerInterceptor(
+		serverinterceptors.WithPayload(0),
+		serverinterceptors.WithRedactFields("inner.name"),
+		serverinterceptors.WithSlowThreshold(0, 20*time.Millisecond),
+		serverinterceptors.WithMethodLevel("/test.Svc/Quiet", logger.LogLevel_Debug),
+	)
+	call := func(method string, handler grpc.UnaryHandler) map[string]any {
+		_, _ = interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return capture.entries[len(capture.entries)-1]
+	}
+
+	entry := call("/test.Svc/Login", func(ctx context.Context, req any) (any, error) { return req, nil })
+	wantPayload := `{"name":"n","password":"******","inner":{"name":"******"}}`
+	if entry["level"] != "info" || entry["code"] != "OK" || compact(entry["request"]) != wantPayload {
+		t.Errorf("entry = %v, want info with redacted payload", entry)
+	}
+	if req.Get(md.Fields().ByName("password")).String() != "p" {
+		t.Error("request must not be modified")
+	}
+
+	entry = call("/test.Svc/Quiet", func(ctx context.Context, req any) (any, error) { return nil, nil })
+	if entry["level"] != "debug" {
+		t.Errorf("level = %v, want debug", entry["level"])
+	}
+
+	entry = call("/test.Svc/Login", func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.InvalidArgument, "bad")
+	})
+	if entry["level"] != "warn" || entry["code"] != "InvalidArgument" {
+		t.Errorf("entry = %v, want warn InvalidArgument", entry)
+	}
+
+	entry = call("/test.Svc/Quiet", func(ctx context.Context, req any) (any, error) {
+		time.Sleep(30 * time.Millisecond)
+		return nil, nil
+	})
+	if entry["level"] != "error" {
+		t.Errorf("level = %v, want error for slow call", entry["level"])
+	}
+}
+
+// compact protojson 输出的空格不稳定, 比较前去除
+func compact(v any) string {
+	s := fmt.Sprint(v)
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r != ' ' {
+			out = append(out, r)
+		}
+	}
+	return string(out)
+}
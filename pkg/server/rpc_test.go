@@ -0,0 +1,42 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/breaker"
+	"github.com/gogoclouds/project-layout/pkg/server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_RpcDialBreaker(t *testing.T) {
+	g, err := breaker.NewGroup(breaker.Config{Kind: breaker.KindClassic, MinRequests: 1, OpenTimeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := server.UseRpcBreaker(g)
+	other, _ := breaker.NewGroup(breaker.Config{Kind: breaker.KindClassic})
+	server.UseRpcBreaker(other)() // 已设置时不替换, release 不影响已有设置
+
+	conn, err := server.RpcDial("127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Get(conn.Target() + "/svc/Method").MarkFailed()
+	invoke := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		return conn.Invoke(ctx, "/svc/Method", nil, nil)
+	}
+	if err = invoke(); status.Code(err) != codes.Unavailable || status.Convert(err).Message() != breaker.ErrNotAllowed.Error() {
+		t.Fatalf("err = %v, want rejected by breaker", err)
+	}
+
+	// 取消后同一连接不再熔断
+	release()
+	if err = invoke(); err == nil || status.Convert(err).Message() == breaker.ErrNotAllowed.Error() {
+		t.Fatalf("err = %v, want call not rejected by breaker", err)
+	}
+}
@@ -1,10 +1,15 @@
 package server
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
 
 const (
-	KindGRPC = "grpc"
-	KindHTTP = "http"
+	KindGRPC  = "grpc"
+	KindHTTP  = "http"
+	KindAdmin = "admin"
 )
 
 // Server is transport server.
@@ -12,3 +17,29 @@ type Server interface {
 	Start(context.Context) error
 	Stop(context.Context) error
 }
+
+// Listener 传输服务在 Start 之前绑定监听地址.
+// App 会先对所有实现该接口的服务调用 Listen, 全部绑定成功后才启动服务并注册到注册中心.
+type Listener interface {
+	Listen() error
+}
+
+// Endpointer 传输服务绑定监听地址后对外暴露的真实地址 (如 :0 端口绑定后的实际端口)
+type Endpointer interface {
+	Endpoint() (*url.URL, error)
+}
+
+// ListenError 传输服务绑定监听地址失败 (如端口被占用)
+type ListenError struct {
+	Kind string // KindHTTP | KindGRPC | KindAdmin
+	Addr string
+	Err  error
+}
+
+func (e *ListenError) Error() string {
+	return fmt.Sprintf("%s server listen on %s: %v", e.Kind, e.Addr, e.Err)
+}
+
+func (e *ListenError) Unwrap() error {
+	return e.Err
+}
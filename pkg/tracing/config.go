@@ -0,0 +1,14 @@
+package tracing
+
+const (
+	ExporterOTLP   = "otlp"   // OTLP gRPC, 如 Jaeger、Tempo、otel-collector
+	ExporterStdout = "stdout" // 输出到控制台, 用于本地调试
+	ExporterFile   = "file"   // 输出到文件, 用于本地调试
+)
+
+type Config struct {
+	Exporter    string  `yaml:"exporter"`    // otlp | stdout | file, 为空时不启用
+	Endpoint    string  `yaml:"endpoint"`    // otlp: collector 地址 127.0.0.1:4317; file: 文件路径
+	Insecure    bool    `yaml:"insecure"`    // otlp 不使用 TLS
+	SampleRatio float64 `yaml:"sampleRatio"` // 采样率 0~1, 默认 0 不采样; 上游已采样的请求始终采样
+}
@@ -0,0 +1,154 @@
+package tracing_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gogoclouds/project-layout/pkg/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// recorder 设置记录 span 的全局 TracerProvider
+func recorder(t *testing.T) (*tracetest.SpanRecorder, trace.Tracer) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return sr, tp.Tracer("test")
+}
+
+func TestGin(t *testing.T) {
+	recorder(t)
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(tracing.GinMiddleware("test"))
+	var sc trace.SpanContext
+	e.GET("/ping", func(c *gin.Context) {
+		sc = trace.SpanContextFromContext(c.Request.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("upstream trace not continued: %s", sc.TraceID())
+	}
+}
+
+func TestGrpc(t *testing.T) {
+	sr, tracer := recorder(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(tracing.ServerOption())
+	healthgrpc.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()), tracing.DialOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, span := tracer.Start(context.Background(), "parent")
+	if _, err = healthgrpc.NewHealthClient(conn).Check(ctx, &healthgrpc.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+	srv.GracefulStop()
+
+	kinds := make(map[trace.SpanKind]bool)
+	for _, s := range sr.Ended() {
+		if s.SpanContext().TraceID() != span.SpanContext().TraceID() {
+			t.Fatalf("span %s not in parent trace", s.Name())
+		}
+		kinds[s.SpanKind()] = true
+	}
+	if !kinds[trace.SpanKindClient] || !kinds[trace.SpanKindServer] {
+		t.Fatalf("expected client and server spans, got: %v", kinds)
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	sr, _ := recorder(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	rdb.AddHook(tracing.NewRedisHook())
+
+	ctx := context.Background()
+	_ = rdb.Set(ctx, "k", "v", 0).Err()
+	_ = rdb.Get(ctx, "missing").Err()
+	names := make(map[string]bool)
+	for _, s := range sr.Ended() {
+		names[s.Name()] = true
+		if s.Name() == "redis.get" && len(s.Events()) > 0 {
+			t.Fatal("redis.Nil recorded as error")
+		}
+	}
+	if !names["redis.set"] || !names["redis.get"] {
+		t.Fatalf("unexpected spans: %v", names)
+	}
+}
+
+func TestInit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.json")
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{Exporter: tracing.ExporterFile, Endpoint: file, SampleRatio: 1}, "test", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "op")
+	span.End()
+	if err = shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(file); len(b) == 0 {
+		t.Fatal("span not exported")
+	}
+
+	if _, err = tracing.Init(context.Background(), tracing.Config{Exporter: "zipkin"}, "test", "v1"); err == nil {
+		t.Fatal("expected unknown exporter error")
+	}
+}
+
+func TestInitSampleRatio(t *testing.T) {
+	ctx := context.Background()
+	for _, ratio := range []float64{-0.1, 1.5} {
+		if _, err := tracing.Init(ctx, tracing.Config{Exporter: tracing.ExporterStdout, SampleRatio: ratio}, "test", "v1"); err == nil {
+			t.Errorf("ratio %v: expected error", ratio)
+		}
+	}
+
+	shutdown, err := tracing.Init(ctx, tracing.Config{
+		Exporter: tracing.ExporterFile,
+		Endpoint: filepath.Join(t.TempDir(), "trace.json"),
+	}, "test", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(ctx)
+	// 0 不采样
+	_, span := otel.Tracer("test").Start(ctx, "root")
+	defer span.End()
+	if span.SpanContext().IsSampled() {
+		t.Fatal("expected root span not sampled with ratio 0")
+	}
+}
@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+)
+
+// GinMiddleware 为每个 http 请求创建 span, 从请求头 (traceparent) 继续上游链路
+func GinMiddleware(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service)
+}
+
+// ServerOption 为每个 rpc 请求创建 span, 从 metadata 继续上游链路
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// DialOption 为每个 rpc 调用创建 span, 并将链路信息写入 metadata 传递给下游
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// Transport 为每个 http 请求创建 span, 并将链路信息写入请求头传递给下游
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next)
+}
@@ -0,0 +1,324 @@
+// Package worker 后台任务 (队列消费者、定时任务等), 实现 server.Server, 由 App 统一启动和停止.
+//  1. 常驻任务: fn 持续运行直到 ctx 取消, 返回错误或 panic 后按退避策略重启, 返回 nil 视为正常结束.
+//  2. 定时任务: WithInterval、WithCron 按计划执行 fn, 同一 Worker 的多次执行不会重叠,
+//     执行失败按退避策略重试, 重试耗尽后等待下一次计划.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/logger"
+	"github.com/gogoclouds/project-layout/pkg/server"
+	"github.com/robfig/cron/v3"
+)
+
+var _ server.Server = (*Worker)(nil)
+
+// State 任务状态
+type State string
+
+const (
+	StateIdle     State = "idle"     // 未启动或等待下一次计划
+	StateRunning  State = "running"  // 执行中
+	StateBackoff  State = "backoff"  // 执行失败, 等待重启
+	StateFailed   State = "failed"   // 重启次数耗尽
+	StateFinished State = "finished" // 常驻任务正常结束
+	StateStopped  State = "stopped"  // 已停止
+)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// Func 任务函数, ctx 在关闭时取消
+type Func func(ctx context.Context) error
+
+// Status 任务运行状态
+type Status struct {
+	Name      string    `json:"name"`
+	State     State     `json:"state"`
+	Restarts  int       `json:"restarts"` // 连续重启次数, 执行成功后清零
+	LastError string    `json:"lastError,omitempty"`
+	LastRun   time.Time `json:"lastRun,omitempty"`
+	NextRun   time.Time `json:"nextRun,omitempty"`
+}
+
+type Option func(w *Worker)
+
+// WithMaxRestarts 连续失败后的最大重启次数, 默认 -1 不限制
+func WithMaxRestarts(n int) Option {
+	return func(w *Worker) {
+		w.maxRestarts = n
+	}
+}
+
+// WithBackoff 重启的指数退避区间, 默认 1s ~ 1m
+func WithBackoff(min, max time.Duration) Option {
+	return func(w *Worker) {
+		w.minBackoff, w.maxBackoff = min, max
+	}
+}
+
+// WithInterval 按固定间隔执行, 间隔从上一次执行结束开始计算
+func WithInterval(d time.Duration) Option {
+	return func(w *Worker) {
+		w.schedule = cron.ConstantDelaySchedule{Delay: d}
+	}
+}
+
+// WithCron 按 cron 表达式执行, 支持标准 5 段格式及 @every 1m、@daily 等描述符
+func WithCron(spec string) Option {
+	return func(w *Worker) {
+		w.schedule, w.err = cron.ParseStandard(spec)
+		if w.err != nil {
+			w.err = fmt.Errorf("worker %s: cron %q: %w", w.name, spec, w.err)
+		}
+	}
+}
+
+// Worker 后台任务
+type Worker struct {
+	name        string
+	fn          Func
+	schedule    cron.Schedule
+	maxRestarts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	err         error // 配置错误, Start 时返回
+
+	mu     sync.Mutex
+	status Status
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New 创建后台任务
+func New(name string, fn Func, opts ...Option) *Worker {
+	w := &Worker{
+		name:        name,
+		fn:          fn,
+		maxRestarts: -1,
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		status:      Status{Name: name, State: StateIdle},
+		done:        make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	return w
+}
+
+func (w *Worker) Name() string {
+	return w.name
+}
+
+// Err 返回配置错误 (如 cron 表达式无效)
+func (w *Worker) Err() error {
+	return w.err
+}
+
+// Status 返回当前运行状态
+func (w *Worker) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Check 健康检查, 重启次数耗尽时返回错误
+func (w *Worker) Check(_ context.Context) error {
+	st := w.Status()
+	if st.State == StateFailed {
+		return fmt.Errorf("worker %s failed after %d restarts: %s", w.name, w.maxRestarts, st.LastError)
+	}
+	return nil
+}
+
+// CheckDetails 同 Check, 并返回当前运行状态 (重启次数、最近错误、下次执行时间等) 作为检查详情,
+// 见 health.Checker.AddDetailCheck
+func (w *Worker) CheckDetails(ctx context.Context) (any, error) {
+	return w.Status(), w.Check(ctx)
+}
+
+// Start 运行任务直到 ctx 取消或 Stop, 重启耗尽不返回错误, 只体现在 Status 和 Check 中.
+// Worker 只能启动一次, 重复调用返回错误.
+func (w *Worker) Start(ctx context.Context) error {
+	if w.err != nil {
+		return w.err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.mu.Unlock()
+		cancel()
+		return fmt.Errorf("worker %s: already started", w.name)
+	}
+	w.cancel = cancel
+	w.mu.Unlock()
+	defer func() {
+		if ctx.Err() != nil {
+			w.setState(StateStopped)
+		}
+		cancel()
+		close(w.done)
+	}()
+
+	if w.schedule == nil {
+		w.runForever(ctx)
+	} else {
+		w.runScheduled(ctx)
+	}
+	return nil
+}
+
+// Stop 取消任务并等待退出, 超出 ctx 时限后返回
+func (w *Worker) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	cancel := w.cancel
+	w.mu.Unlock()
+	if cancel == nil {
+		// 未启动
+		return nil
+	}
+	cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("worker %s: %w", w.name, ctx.Err())
+	}
+}
+
+// runForever 常驻任务, 失败后按退避策略重启
+func (w *Worker) runForever(ctx context.Context) {
+	for {
+		start := time.Now()
+		err := w.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			w.setState(StateFinished)
+			logger.Infof("worker %s finished", w.name)
+			return
+		}
+		// 运行足够久后再失败视为新的故障, 重新计算退避
+		if time.Since(start) > w.maxBackoff {
+			w.resetRestarts()
+		}
+		if !w.backoff(ctx, err) {
+			return
+		}
+	}
+}
+
+// runScheduled 定时任务, 失败后按退避策略重试, 重试耗尽后等待下一次计划
+func (w *Worker) runScheduled(ctx context.Context) {
+	for {
+		next := w.schedule.Next(time.Now())
+		w.mu.Lock()
+		w.status.NextRun = next
+		// 每次计划重新计算重试次数, 上一次计划的失败状态保留到本次执行成功
+		w.status.Restarts = 0
+		if w.status.State != StateFailed {
+			w.status.State = StateIdle
+		}
+		w.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		for {
+			err := w.run(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				w.resetRestarts()
+				break
+			}
+			if !w.backoff(ctx, err) {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// run 执行一次 fn, panic 转为错误
+func (w *Worker) run(ctx context.Context) (err error) {
+	w.mu.Lock()
+	w.status.State = StateRunning
+	w.status.LastRun = time.Now()
+	w.mu.Unlock()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+			logger.Errorf("worker %s panic: %v\n%s", w.name, p, debug.Stack())
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			w.mu.Lock()
+			w.status.LastError = err.Error()
+			w.mu.Unlock()
+		}
+	}()
+	return w.fn(ctx)
+}
+
+// backoff 记录失败并等待退避时间, 重启次数耗尽或 ctx 取消时返回 false
+func (w *Worker) backoff(ctx context.Context, err error) bool {
+	w.mu.Lock()
+	if w.maxRestarts >= 0 && w.status.Restarts >= w.maxRestarts {
+		w.status.State = StateFailed
+		w.mu.Unlock()
+		logger.Errorf("worker %s failed after %d restarts: %v", w.name, w.maxRestarts, err)
+		return false
+	}
+	d := w.minBackoff << w.status.Restarts
+	if d > w.maxBackoff || d <= 0 {
+		d = w.maxBackoff
+	}
+	w.status.Restarts++
+	w.status.State = StateBackoff
+	restarts := w.status.Restarts
+	w.mu.Unlock()
+
+	logger.Errorf("worker %s error: %v, restart #%d in %s", w.name, err, restarts, d)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (w *Worker) resetRestarts() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.Restarts = 0
+	w.status.LastError = ""
+	if w.status.State == StateFailed {
+		w.status.State = StateIdle
+	}
+}
+
+func (w *Worker) setState(state State) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status.State = state
+}
@@ -0,0 +1,109 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogoclouds/project-layout/pkg/worker"
+)
+
+// waitState 等待任务进入指定状态
+func waitState(t *testing.T, w *worker.Worker, state worker.State) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if w.Status().State == state {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("worker state %s, want %s", w.Status().State, state)
+}
+
+func TestRestart(t *testing.T) {
+	var runs atomic.Int32
+	w := worker.New("restart", func(ctx context.Context) error {
+		if runs.Add(1) == 2 {
+			panic("boom")
+		}
+		return errors.New("consume failed")
+	}, worker.WithMaxRestarts(2), worker.WithBackoff(time.Millisecond, 5*time.Millisecond))
+
+	go w.Start(context.Background())
+	waitState(t, w, worker.StateFailed)
+	if n := runs.Load(); n != 3 {
+		t.Fatalf("expected 3 runs, got %d", n)
+	}
+	if err := w.Check(context.Background()); err == nil {
+		t.Fatal("expected check error")
+	}
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStop(t *testing.T) {
+	w := worker.New("consumer", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	go w.Start(context.Background())
+	waitState(t, w, worker.StateRunning)
+
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if st := w.Status(); st.State != worker.StateStopped || st.LastError != "" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	// 不能重复启动
+	if err := w.Start(context.Background()); err == nil {
+		t.Fatal("expected already started error")
+	}
+
+	// 忽略取消的任务超出停止时限
+	w = worker.New("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	go w.Start(context.Background())
+	waitState(t, w, worker.StateRunning)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	var runs atomic.Int32
+	w := worker.New("interval", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, worker.WithInterval(10*time.Millisecond))
+	go w.Start(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := runs.Load(); n < 3 {
+		t.Fatalf("expected at least 3 runs, got %d", n)
+	}
+
+	w = worker.New("cron", func(ctx context.Context) error { return nil }, worker.WithCron("*/5 * * *"))
+	if err := w.Err(); err == nil {
+		t.Fatal("expected cron spec error")
+	}
+	w = worker.New("cron", func(ctx context.Context) error { return nil }, worker.WithCron("@every 1h"))
+	go w.Start(context.Background())
+	for i := 0; i < 100 && w.Status().NextRun.IsZero(); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if next := w.Status().NextRun; time.Until(next) < 59*time.Minute {
+		t.Fatalf("unexpected next run: %s", next)
+	}
+	_ = w.Stop(context.Background())
+}